@@ -11,9 +11,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
 	"github.com/alejandro/video-worker/internal/adapters/ffmpeg"
+	metricsadapter "github.com/alejandro/video-worker/internal/adapters/metrics"
 	"github.com/alejandro/video-worker/internal/core/ports"
-	"go.uber.org/zap"
 )
 
 func main() {
@@ -67,7 +70,11 @@ func processVideoFile(ctx context.Context, inputPath string) (string, error) {
 		}()
 	}
 
-	processor := ffmpeg.NewVideoProcessor(os.Getenv("FFMPEG_PATH"), os.Getenv("FFPROBE_PATH"), os.Getenv("VIDEO_TEMP_DIR"), logger)
+	metricsAdapter := metricsadapter.NewPrometheusMetrics(prometheus.NewRegistry())
+	processor := ffmpeg.NewVideoProcessor(
+		os.Getenv("FFMPEG_PATH"), os.Getenv("FFPROBE_PATH"), os.Getenv("VIDEO_TEMP_DIR"), os.Getenv("FFMPEG_FORCE_ENCODER"),
+		0, logger, metricsAdapter,
+	)
 	processed, err := processor.Process(ctx, file, ports.VideoProcessingOptions{
 		ClipDuration: 30 * time.Second,
 		TargetWidth:  720,
@@ -0,0 +1,137 @@
+// Package adminapi exposes HTTP endpoints over a ports.DeadLetterStore so
+// operators can triage and replay poison video tasks without writing
+// ad-hoc redis-cli scripts.
+package adminapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// NewHandler builds the dead-letter admin HTTP handler backed by store:
+//
+//	GET  /admin/dlq               list entries (query params: cursor, limit)
+//	GET  /admin/dlq/{id}           fetch one entry
+//	POST /admin/dlq/{id}/requeue   replay an entry onto the main queue
+//	POST /admin/dlq/purge          delete entries (query param: id, repeatable)
+func NewHandler(store ports.DeadLetterStore, logger *zap.Logger) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	h := &handler{store: store, logger: logger}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/dlq", h.handleCollection)
+	mux.HandleFunc("/admin/dlq/", h.handleItem)
+	return mux
+}
+
+type handler struct {
+	store  ports.DeadLetterStore
+	logger *zap.Logger
+}
+
+func (h *handler) handleCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil {
+			limit = n
+		}
+	}
+
+	entries, next, err := h.store.List(r.Context(), cursor, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"entries":     entries,
+		"next_cursor": next,
+	})
+}
+
+func (h *handler) handleItem(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/admin/dlq/")
+	if rest == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if rest == "purge" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.purge(w, r)
+		return
+	}
+
+	id, action, _ := strings.Cut(rest, "/")
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.get(w, r, id)
+	case action == "requeue" && r.Method == http.MethodPost:
+		h.requeue(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request, id string) {
+	entry, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, entry)
+}
+
+func (h *handler) requeue(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.store.Requeue(r.Context(), id); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"id": id, "status": "requeued"})
+}
+
+func (h *handler) purge(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query()["id"]
+	if len(ids) == 0 {
+		http.Error(w, "at least one id query parameter is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.Purge(r.Context(), ids...); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"purged": ids})
+}
+
+func (h *handler) writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ports.ErrDeadLetterNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Warn("failed to encode admin api response", zap.Error(err))
+	}
+}
@@ -5,26 +5,99 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	AppName            string
-	LogLevel           string
-	RedisAddr          string
-	RedisUsername      string
-	RedisPassword      string
-	RedisStream        string
-	RedisGroup         string
-	RedisConsumer      string
-	RedisBlockTimeout  time.Duration
-	RedisMaxDeliveries int
-	WorkerPoolSize     int
-	ProcessingTimeout  time.Duration
-	PostgresDSN        string
+	AppName           string
+	LogLevel          string
+	RedisAddr         string
+	RedisUsername     string
+	RedisPassword     string
+	RedisStream       string
+	RedisGroup        string
+	RedisConsumer     string
+	RedisBlockTimeout time.Duration
+	// RedisClaimMinIdle is how long a pending message must sit
+	// unacknowledged before the reaper's XAUTOCLAIM sweep claims it from a
+	// crashed consumer. Zero defaults to 5*ProcessingTimeout.
+	RedisClaimMinIdle time.Duration
+	// RedisClaimInterval is how often the reaper sweeps for idle pending
+	// messages.
+	RedisClaimInterval time.Duration
+	// RedisDLQStream is the stream Fail and the reclaimer write a task to
+	// once it exhausts MaxDeliveries, instead of dropping it.
+	RedisDLQStream string
+	// RetryBaseDelay/RetryMaxDelay/RetryJitter configure the exponential
+	// backoff StreamQueue.Fail applies before a failed task becomes
+	// eligible for redelivery: base * 2^attempt, capped at max, plus up to
+	// jitter of random slack.
+	RetryBaseDelay    time.Duration
+	RetryMaxDelay     time.Duration
+	RetryJitter       time.Duration
+	WorkerPoolSize    int
+	ProcessingTimeout time.Duration
+	PostgresDSN       string
+
+	// QueueBackend selects which MessageQueue implementation workers use:
+	// "redis", "sqs", "kafka", or "nats".
+	QueueBackend string
+	// MaxDeliveries caps how many times a task may be delivered before the
+	// queue backend gives up on it, shared across backends.
+	MaxDeliveries int
+
+	// SQS configuration
+	SQSQueueURL string
+	SQSRegion   string
+	SQSWaitTime int32
+	// SQSDeadLetterQueueURL receives exhausted tasks with failure metadata
+	// when set. Optional: an empty value just drops the task after logging.
+	SQSDeadLetterQueueURL string
+	// SQSRetryBaseDelay/SQSRetryMaxDelay/SQSRetryJitter configure the
+	// exponential backoff SQSQueue.Fail uses when scheduling a retry via
+	// ChangeMessageVisibility.
+	SQSRetryBaseDelay time.Duration
+	SQSRetryMaxDelay  time.Duration
+	SQSRetryJitter    time.Duration
+
+	// Kafka configuration
+	KafkaBrokers []string
+	KafkaTopic   string
+	KafkaGroupID string
+	// KafkaDLQTopic receives exhausted tasks with failure metadata once a
+	// task exceeds MaxDeliveries, instead of being discarded. Empty defaults
+	// to KafkaTopic + ":dlq".
+	KafkaDLQTopic string
+	// KafkaRetryTopic holds failed tasks until their backoff delay elapses,
+	// so Fail doesn't hot-loop a retry straight back onto KafkaTopic. Empty
+	// defaults to KafkaTopic + ":retry".
+	KafkaRetryTopic string
+	// KafkaRetryBaseDelay/KafkaRetryMaxDelay/KafkaRetryJitter configure the
+	// exponential backoff kafka.Queue.Fail applies before a failed task
+	// becomes eligible for redelivery via KafkaRetryTopic.
+	KafkaRetryBaseDelay time.Duration
+	KafkaRetryMaxDelay  time.Duration
+	KafkaRetryJitter    time.Duration
+
+	// NATS JetStream configuration
+	NATSURL      string
+	NATSStream   string
+	NATSConsumer string
+
+	// FFmpegWorkerPoolSize bounds the number of concurrent ffmpeg/ffprobe
+	// subprocesses, independent of how many queue consumers are running.
+	FFmpegWorkerPoolSize int
+	// FFmpegJobQueueSize bounds how many submitted jobs may wait for a free
+	// worker before Submit blocks the calling consumer.
+	FFmpegJobQueueSize int
+	// ChunkWorkerPoolSize bounds how many chunks of a ChunkSeconds-driven
+	// encode transcode concurrently. Zero defaults to runtime.NumCPU().
+	ChunkWorkerPoolSize int
 
 	// Storage backend selection
 	StorageBackend string // "nextcloud" or "s3"
@@ -41,10 +114,54 @@ type Config struct {
 	S3AccessKey string
 	S3SecretKey string
 	S3Endpoint  string
+	// S3PartSizeBytes is the size of each multipart upload part. S3 requires
+	// at least 5 MiB per part (except the last one); the adapter enforces
+	// that floor regardless of this value.
+	S3PartSizeBytes int64
+	// S3UploadConcurrency bounds how many parts of a single multipart upload
+	// are in flight at once.
+	S3UploadConcurrency int
 
 	ProcessedBaseURL string
 	MetricsAddr      string
 	ShutdownGrace    time.Duration
+
+	// PresignedURLExpiry is how long a presigned playback URL for a
+	// processed video stays valid. Zero disables presigning; the use case
+	// then persists only the raw processed path.
+	PresignedURLExpiry time.Duration
+
+	// TaskLeaseTTL is how long a TaskLedger claim is held before the
+	// reaper considers it stale. Should comfortably exceed the time a
+	// single task takes to process.
+	TaskLeaseTTL time.Duration
+	// TaskReaperInterval is how often the TaskLedger reaper sweeps for
+	// expired leases.
+	TaskReaperInterval time.Duration
+
+	// LockEnabled turns on the redis TaskLocker that guards against two
+	// workers concurrently transcoding the same VideoID after a requeue or
+	// reclaim. Only takes effect when QUEUE_BACKEND=redis.
+	LockEnabled bool
+	// LockTTL is how long an acquired video lock lives before it's
+	// considered abandoned by a crashed worker. Should comfortably exceed
+	// ProcessingTimeout.
+	LockTTL time.Duration
+	// LockRefreshInterval is how often HandleNext extends a held lock's TTL
+	// while processing is still in flight. Zero defaults to LockTTL/3.
+	LockRefreshInterval time.Duration
+
+	// VideoTempDir is where temp input/output files (ffmpeg and spooled
+	// downloads) are created. Empty means os.TempDir().
+	VideoTempDir string
+	// DownloadSpoolThreshold is the largest download, in bytes, storage
+	// adapters will still buffer entirely in memory; anything larger is
+	// streamed to a temp file under VideoTempDir instead.
+	DownloadSpoolThreshold int64
+
+	// OutputFormat selects the shape Process produces: "mp4" (default,
+	// single progressive file), "dash", or "hls".
+	OutputFormat string
 }
 
 func Load(envPaths ...string) (*Config, error) {
@@ -69,11 +186,44 @@ func Load(envPaths ...string) (*Config, error) {
 		RedisGroup:         getEnv("REDIS_GROUP", "video_worker"),
 		RedisConsumer:      getEnv("REDIS_CONSUMER", "video_worker_1"),
 		RedisBlockTimeout:  getDurationEnv("REDIS_BLOCK_TIMEOUT", 5*time.Second),
-		RedisMaxDeliveries: getIntEnv("REDIS_MAX_DELIVERIES", 5),
+		RedisClaimMinIdle:  getDurationEnv("REDIS_CLAIM_MIN_IDLE", 0),
+		RedisClaimInterval: getDurationEnv("REDIS_CLAIM_INTERVAL", time.Minute),
+		RedisDLQStream:     getEnv("REDIS_DLQ_STREAM", "video_tasks:dlq"),
+		RetryBaseDelay:     getDurationEnv("RETRY_BASE_DELAY", time.Second),
+		RetryMaxDelay:      getDurationEnv("RETRY_MAX_DELAY", 5*time.Minute),
+		RetryJitter:        getDurationEnv("RETRY_JITTER", 250*time.Millisecond),
 		WorkerPoolSize:     getIntEnv("WORKER_POOL_SIZE", 4),
 		ProcessingTimeout:  getDurationEnv("PROCESSING_TIMEOUT", 5*time.Minute),
 		PostgresDSN:        os.Getenv("POSTGRES_DSN"),
 
+		QueueBackend:  getEnv("QUEUE_BACKEND", "redis"),
+		MaxDeliveries: getIntEnv("MAX_DELIVERIES", 5),
+
+		SQSQueueURL:           os.Getenv("SQS_QUEUE_URL"),
+		SQSRegion:             getEnv("SQS_REGION", "us-east-1"),
+		SQSWaitTime:           int32(getIntEnv("SQS_WAIT_TIME_SECONDS", 10)),
+		SQSDeadLetterQueueURL: os.Getenv("SQS_DLQ_URL"),
+		SQSRetryBaseDelay:     getDurationEnv("SQS_RETRY_BASE_DELAY", time.Second),
+		SQSRetryMaxDelay:      getDurationEnv("SQS_RETRY_MAX_DELAY", 5*time.Minute),
+		SQSRetryJitter:        getDurationEnv("SQS_RETRY_JITTER", time.Second),
+
+		KafkaBrokers:        getStringSliceEnv("KAFKA_BROKERS", nil),
+		KafkaTopic:          getEnv("KAFKA_TOPIC", "video_tasks"),
+		KafkaGroupID:        getEnv("KAFKA_GROUP_ID", "video_worker"),
+		KafkaDLQTopic:       os.Getenv("KAFKA_DLQ_TOPIC"),
+		KafkaRetryTopic:     os.Getenv("KAFKA_RETRY_TOPIC"),
+		KafkaRetryBaseDelay: getDurationEnv("KAFKA_RETRY_BASE_DELAY", time.Second),
+		KafkaRetryMaxDelay:  getDurationEnv("KAFKA_RETRY_MAX_DELAY", 5*time.Minute),
+		KafkaRetryJitter:    getDurationEnv("KAFKA_RETRY_JITTER", time.Second),
+
+		NATSURL:      os.Getenv("NATS_URL"),
+		NATSStream:   getEnv("NATS_STREAM", "video_tasks"),
+		NATSConsumer: getEnv("NATS_CONSUMER", "video_worker"),
+
+		FFmpegWorkerPoolSize: getIntEnv("FFMPEG_WORKER_POOL_SIZE", runtime.NumCPU()),
+		FFmpegJobQueueSize:   getIntEnv("FFMPEG_JOB_QUEUE_SIZE", 0),
+		ChunkWorkerPoolSize:  getIntEnv("CHUNK_WORKER_POOL_SIZE", runtime.NumCPU()),
+
 		// Storage backend selection
 		StorageBackend: getEnv("STORAGE_BACKEND", "s3"),
 
@@ -84,21 +234,49 @@ func Load(envPaths ...string) (*Config, error) {
 		NextcloudPassword: os.Getenv("NEXTCLOUD_PASSWORD"),
 
 		// S3 configuration
-		S3Region:    getEnv("S3_REGION", "us-east-1"),
-		S3Bucket:    os.Getenv("S3_BUCKET"),
-		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
-		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
-		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:            getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:            os.Getenv("S3_BUCKET"),
+		S3AccessKey:         os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:         os.Getenv("S3_SECRET_KEY"),
+		S3Endpoint:          os.Getenv("S3_ENDPOINT"),
+		S3PartSizeBytes:     int64(getIntEnv("S3_PART_SIZE_BYTES", 8*1024*1024)),
+		S3UploadConcurrency: getIntEnv("S3_UPLOAD_CONCURRENCY", 4),
 
 		ProcessedBaseURL: getEnv("PROCESSED_BASE_URL", "processed/"),
 		MetricsAddr:      getEnv("METRICS_ADDR", ":9090"),
 		ShutdownGrace:    getDurationEnv("SHUTDOWN_GRACE", 30*time.Second),
+
+		PresignedURLExpiry: getDurationEnv("PRESIGNED_URL_EXPIRY", time.Hour),
+
+		TaskLeaseTTL:       getDurationEnv("TASK_LEASE_TTL", 10*time.Minute),
+		TaskReaperInterval: getDurationEnv("TASK_REAPER_INTERVAL", time.Minute),
+
+		LockEnabled:         getBoolEnv("LOCK_ENABLED", true),
+		LockTTL:             getDurationEnv("LOCK_TTL", 0),
+		LockRefreshInterval: getDurationEnv("LOCK_REFRESH_INTERVAL", 0),
+
+		VideoTempDir:           os.Getenv("VIDEO_TEMP_DIR"),
+		DownloadSpoolThreshold: int64(getIntEnv("DOWNLOAD_SPOOL_THRESHOLD_BYTES", 16*1024*1024)),
+
+		OutputFormat: getEnv("OUTPUT_FORMAT", "mp4"),
 	}
 
 	if cfg.PostgresDSN == "" {
 		return nil, fmt.Errorf("POSTGRES_DSN is required")
 	}
 
+	if cfg.RedisClaimMinIdle <= 0 {
+		cfg.RedisClaimMinIdle = 5 * cfg.ProcessingTimeout
+	}
+
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = cfg.ProcessingTimeout + 2*time.Minute
+	}
+
+	if cfg.RetryMaxDelay < cfg.RetryBaseDelay {
+		return nil, fmt.Errorf("RETRY_MAX_DELAY (%s) must be >= RETRY_BASE_DELAY (%s)", cfg.RetryMaxDelay, cfg.RetryBaseDelay)
+	}
+
 	// Validate storage backend configuration
 	switch cfg.StorageBackend {
 	case "nextcloud":
@@ -117,6 +295,31 @@ func Load(envPaths ...string) (*Config, error) {
 		return nil, fmt.Errorf("STORAGE_BACKEND must be 'nextcloud' or 's3', got: %s", cfg.StorageBackend)
 	}
 
+	// Validate queue backend configuration
+	switch cfg.QueueBackend {
+	case "redis":
+	case "sqs":
+		if cfg.SQSQueueURL == "" {
+			return nil, fmt.Errorf("SQS_QUEUE_URL is required when QUEUE_BACKEND=sqs")
+		}
+	case "kafka":
+		if len(cfg.KafkaBrokers) == 0 {
+			return nil, fmt.Errorf("KAFKA_BROKERS is required when QUEUE_BACKEND=kafka")
+		}
+	case "nats":
+		if cfg.NATSURL == "" {
+			return nil, fmt.Errorf("NATS_URL is required when QUEUE_BACKEND=nats")
+		}
+	default:
+		return nil, fmt.Errorf("QUEUE_BACKEND must be 'redis', 'sqs', 'kafka' or 'nats', got: %s", cfg.QueueBackend)
+	}
+
+	switch cfg.OutputFormat {
+	case "mp4", "dash", "hls":
+	default:
+		return nil, fmt.Errorf("OUTPUT_FORMAT must be 'mp4', 'dash' or 'hls', got: %s", cfg.OutputFormat)
+	}
+
 	return cfg, nil
 }
 
@@ -152,6 +355,15 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getBoolEnv(key string, fallback bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if parsed, err := strconv.ParseBool(val); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
 func getIntEnv(key string, fallback int) int {
 	if val := os.Getenv(key); val != "" {
 		if parsed, err := strconv.Atoi(val); err == nil {
@@ -161,6 +373,26 @@ func getIntEnv(key string, fallback int) int {
 	return fallback
 }
 
+// getStringSliceEnv splits a comma-separated env var into a trimmed,
+// non-empty slice, e.g. KAFKA_BROKERS=broker1:9092,broker2:9092.
+func getStringSliceEnv(key string, fallback []string) []string {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	parts := strings.Split(val, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if trimmed := strings.TrimSpace(p); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	if len(out) == 0 {
+		return fallback
+	}
+	return out
+}
+
 func getDurationEnv(key string, fallback time.Duration) time.Duration {
 	if val := os.Getenv(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
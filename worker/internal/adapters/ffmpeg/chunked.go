@@ -0,0 +1,415 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// chunkRange is one [start, end) slice of the content timeline (in
+// seconds, excluding curtains) that encodeChunked transcodes independently.
+type chunkRange struct {
+	index      int
+	start, end float64
+}
+
+// encodeChunked implements the chunked pipeline: it splits
+// [0, clipSeconds) into ChunkSeconds-ish pieces snapped to keyframes,
+// transcodes them concurrently through a bounded worker pool, then
+// concat-demuxes the encoded chunks with the curtain segments instead of
+// running everything through a single ffmpeg filter graph. Unlike the
+// single-pass pipeline, each chunk's watermark window is evaluated against
+// chunk-local time rather than a single filter graph spanning the whole
+// clip, so the same watermark.StartDuration/EndDuration settings are
+// reproduced per chunk by intersecting them with that chunk's range.
+func (p *VideoProcessor) encodeChunked(ctx context.Context, inputPath string, opts ports.VideoProcessingOptions, width, height int, frameRate string, clipSeconds float64, encoder encoderProfile) (*ports.ProcessedVideo, error) {
+	keyframes, err := p.keyframeTimestamps(ctx, inputPath)
+	if err != nil {
+		p.logger.Warn("ffmpeg processor: keyframe probe failed, chunk boundaries won't be keyframe-snapped", zap.Error(err))
+	}
+
+	ranges := chunkBoundaries(clipSeconds, opts.ChunkSeconds, keyframes)
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("ffmpeg processor: chunked encode produced no chunk ranges")
+	}
+
+	var watermarkCfg *watermarkConfig
+	if opts.Watermark != nil {
+		watermarkCfg = normalizeWatermark(opts.Watermark, clipSeconds)
+	}
+
+	curtainPath, cleanupCurtain, err := p.encodeCurtain(ctx, width, height, frameRate, curtainSegmentDuration.Seconds(), watermarkCfg, encoder)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg processor: encode curtain: %w", err)
+	}
+	defer cleanupCurtain()
+
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	poolSize := p.chunkPoolSize
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	if poolSize > len(ranges) {
+		poolSize = len(ranges)
+	}
+
+	chunkPaths := make([]string, len(ranges))
+	errs := make([]error, len(ranges))
+	sem := make(chan struct{}, poolSize)
+	var wg sync.WaitGroup
+	for _, r := range ranges {
+		r := r
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			path, err := p.encodeChunk(chunkCtx, inputPath, r, width, height, frameRate, watermarkCfg, clipSeconds, encoder, opts)
+			if err != nil {
+				errs[r.index] = err
+				cancel()
+				return
+			}
+			chunkPaths[r.index] = path
+		}()
+	}
+	wg.Wait()
+
+	defer func() {
+		for _, path := range chunkPaths {
+			if path != "" {
+				_ = os.Remove(path)
+			}
+		}
+	}()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg processor: chunked encode: %w", err)
+		}
+	}
+
+	outputPath, err := p.concatChunks(ctx, curtainPath, chunkPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := os.Open(outputPath)
+	if err != nil {
+		_ = os.Remove(outputPath)
+		return nil, fmt.Errorf("ffmpeg processor: open output: %w", err)
+	}
+
+	totalDuration := time.Duration(clipSeconds*float64(time.Second)) + 2*curtainSegmentDuration
+	metadata := map[string]string{
+		"clip_duration_seconds":   fmt.Sprintf("%.3f", clipSeconds),
+		"curtain_segment_seconds": fmt.Sprintf("%.3f", curtainSegmentDuration.Seconds()),
+		"total_duration_seconds":  fmt.Sprintf("%.3f", totalDuration.Seconds()),
+		"frame_rate":              frameRate,
+		"target_width":            strconv.Itoa(width),
+		"target_height":           strconv.Itoa(height),
+		"chunk_count":             strconv.Itoa(len(ranges)),
+	}
+
+	return &ports.ProcessedVideo{
+		Reader:   &tempFileReadCloser{File: reader, path: outputPath},
+		Format:   "mp4",
+		Duration: totalDuration,
+		Metadata: metadata,
+	}, nil
+}
+
+// encodeChunk transcodes one chunk of the source in isolation: scale/pad to
+// the canvas, re-apply the watermark's local window (if any falls inside
+// this chunk), and encode with encoder.
+func (p *VideoProcessor) encodeChunk(ctx context.Context, inputPath string, r chunkRange, width, height int, frameRate string, wm *watermarkConfig, clipSeconds float64, encoder encoderProfile, opts ports.VideoProcessingOptions) (string, error) {
+	started := time.Now()
+	defer func() { p.metrics.ObserveChunkDuration(time.Since(started)) }()
+
+	baseFilters := []string{
+		fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", width, height),
+		fmt.Sprintf("pad=%d:%d:(%d-iw)/2:(%d-ih)/2", width, height, width, height),
+		"setsar=1",
+		"format=yuv420p",
+	}
+	if frameRate != "" {
+		baseFilters = append(baseFilters, fmt.Sprintf("fps=%s", frameRate))
+	}
+	filterParts := []string{fmt.Sprintf("[0:v]%s[vchunk]", strings.Join(baseFilters, ","))}
+	mainLabel := "vchunk"
+
+	windows := localWatermarkWindows(wm, r.start, r.end, clipSeconds)
+	var enableExpr string
+	if len(windows) > 0 {
+		parts := make([]string, len(windows))
+		for i, w := range windows {
+			parts[i] = fmt.Sprintf("between(t,%.3f,%.3f)", w[0], w[1])
+		}
+		enableExpr = strings.Join(parts, "+")
+		drawArgs := buildDrawTextArgs(wm, enableExpr)
+		filterParts = append(filterParts, fmt.Sprintf("[%s]drawtext=%s[vchunk_wm]", mainLabel, drawArgs))
+		mainLabel = "vchunk_wm"
+	}
+	if len(windows) > 0 && wm.ImagePath != "" {
+		filterParts = append(filterParts, imageOverlayNodes(wm, mainLabel, "vchunk_img", 1, enableExpr)...)
+		mainLabel = "vchunk_img"
+	}
+	if node := encoder.hwUploadFilter(mainLabel, "vchunk_enc"); node != "" {
+		filterParts = append(filterParts, node)
+		mainLabel = "vchunk_enc"
+	}
+	filter := strings.Join(filterParts, ";")
+
+	outputFile, err := os.CreateTemp(p.tempDir, fmt.Sprintf("ffmpeg-chunk-%03d-*.mp4", r.index))
+	if err != nil {
+		return "", fmt.Errorf("create temp chunk output: %w", err)
+	}
+	outputPath := outputFile.Name()
+	if err := outputFile.Close(); err != nil {
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("close temp chunk output: %w", err)
+	}
+
+	args := []string{"-y"}
+	args = append(args, encoder.GlobalArgs...)
+	args = append(args, "-ss", fmt.Sprintf("%.3f", r.start), "-to", fmt.Sprintf("%.3f", r.end), "-i", inputPath)
+	if wm != nil && wm.ImagePath != "" {
+		args = append(args, "-i", wm.ImagePath)
+	}
+	args = append(args, "-filter_complex", filter, "-map", fmt.Sprintf("[%s]", mainLabel))
+	if opts.RemoveAudio {
+		args = append(args, "-an")
+	} else {
+		args = append(args, "-map", "0:a:0?", "-c:a", "aac", "-b:a", fmt.Sprintf("%dk", defaultAudioBitrateKbps))
+	}
+	args = append(args, encoder.singleEncodeArgs()...)
+	args = append(args, outputPath)
+
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("chunk %d encode failed: %w: %s", r.index, err, stderr.String())
+	}
+	return outputPath, nil
+}
+
+// encodeCurtain renders one curtain segment (the same clip plays at both
+// the start and end of the final concat, so it's only encoded once).
+func (p *VideoProcessor) encodeCurtain(ctx context.Context, width, height int, frameRate string, seconds float64, wm *watermarkConfig, encoder encoderProfile) (string, func(), error) {
+	curtainBase := fmt.Sprintf("color=c=black:size=%dx%d:rate=%s:d=%.3f,format=yuv420p,setsar=1", width, height, frameRate, seconds)
+	filterParts := []string{fmt.Sprintf("%s[vcurtain]", curtainBase)}
+	mainLabel := "vcurtain"
+	if wm != nil {
+		drawArgs := buildDrawTextArgs(wm, "")
+		filterParts = append(filterParts, fmt.Sprintf("[%s]drawtext=%s[vcurtain_wm]", mainLabel, drawArgs))
+		mainLabel = "vcurtain_wm"
+	}
+	if wm != nil && wm.ImagePath != "" {
+		filterParts = append(filterParts, imageOverlayNodes(wm, mainLabel, "vcurtain_img", 0, "")...)
+		mainLabel = "vcurtain_img"
+	}
+	if node := encoder.hwUploadFilter(mainLabel, "vcurtain_enc"); node != "" {
+		filterParts = append(filterParts, node)
+		mainLabel = "vcurtain_enc"
+	}
+	filter := strings.Join(filterParts, ";")
+
+	outputFile, err := os.CreateTemp(p.tempDir, "ffmpeg-curtain-*.mp4")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp curtain output: %w", err)
+	}
+	outputPath := outputFile.Name()
+	if err := outputFile.Close(); err != nil {
+		_ = os.Remove(outputPath)
+		return "", nil, fmt.Errorf("close temp curtain output: %w", err)
+	}
+
+	args := []string{"-y"}
+	args = append(args, encoder.GlobalArgs...)
+	if wm != nil && wm.ImagePath != "" {
+		args = append(args, "-i", wm.ImagePath)
+	}
+	args = append(args, "-filter_complex", filter, "-map", fmt.Sprintf("[%s]", mainLabel))
+	args = append(args, encoder.singleEncodeArgs()...)
+	args = append(args, "-t", fmt.Sprintf("%.3f", seconds), outputPath)
+
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(outputPath)
+		return "", nil, fmt.Errorf("curtain encode failed: %w: %s", err, stderr.String())
+	}
+
+	return outputPath, func() { _ = os.Remove(outputPath) }, nil
+}
+
+// concatChunks stitches curtainPath, chunkPaths (in order), and curtainPath
+// again into one mp4 via ffmpeg's concat demuxer. Every input was already
+// encoded by this processor with matching codec parameters, so this step is
+// a stream copy: no re-encoding happens at the joins.
+func (p *VideoProcessor) concatChunks(ctx context.Context, curtainPath string, chunkPaths []string) (string, error) {
+	outputFile, err := os.CreateTemp(p.tempDir, "ffmpeg-chunked-output-*.mp4")
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg processor: create temp output: %w", err)
+	}
+	outputPath := outputFile.Name()
+	if err := outputFile.Close(); err != nil {
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("ffmpeg processor: close temp output: %w", err)
+	}
+
+	listFile, err := os.CreateTemp(p.tempDir, "ffmpeg-concat-*.txt")
+	if err != nil {
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("ffmpeg processor: create concat list: %w", err)
+	}
+	listPath := listFile.Name()
+	defer func() { _ = os.Remove(listPath) }()
+
+	entries := append([]string{curtainPath}, chunkPaths...)
+	entries = append(entries, curtainPath)
+
+	var list strings.Builder
+	for _, path := range entries {
+		fmt.Fprintf(&list, "file '%s'\n", filepath.ToSlash(path))
+	}
+	if _, err := listFile.WriteString(list.String()); err != nil {
+		_ = listFile.Close()
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("ffmpeg processor: write concat list: %w", err)
+	}
+	if err := listFile.Close(); err != nil {
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("ffmpeg processor: close concat list: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, "-y", "-f", "concat", "-safe", "0", "-i", listPath, "-c", "copy", "-movflags", "+faststart", outputPath)
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(outputPath)
+		return "", fmt.Errorf("ffmpeg processor: concat chunks failed: %w: %s", err, stderr.String())
+	}
+	return outputPath, nil
+}
+
+// keyframeTimestamps returns every video keyframe's presentation timestamp,
+// in seconds, so chunk boundaries can be snapped to one.
+func (p *VideoProcessor) keyframeTimestamps(ctx context.Context, inputPath string) ([]float64, error) {
+	cmd := exec.CommandContext(ctx, p.ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-skip_frame", "nokey",
+		"-show_entries", "frame=pts_time",
+		"-of", "csv=p=0",
+		inputPath,
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe keyframes: %w: %s", err, string(output))
+	}
+
+	var timestamps []float64
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		ts, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			continue
+		}
+		timestamps = append(timestamps, ts)
+	}
+	return timestamps, nil
+}
+
+// chunkBoundaries picks chunkSeconds-ish cut points across [0, total),
+// snapping every interior point to the nearest keyframe at or before it so
+// each chunk starts exactly on a keyframe and concatChunks' stream copy
+// doesn't need to re-encode across the join.
+func chunkBoundaries(total, chunkSeconds float64, keyframes []float64) []chunkRange {
+	if chunkSeconds <= 0 || total <= 0 {
+		return nil
+	}
+
+	cuts := []float64{0}
+	for target := chunkSeconds; target < total; target += chunkSeconds {
+		cuts = append(cuts, snapToKeyframe(target, keyframes))
+	}
+	cuts = append(cuts, total)
+
+	ranges := make([]chunkRange, 0, len(cuts)-1)
+	for i := 0; i < len(cuts)-1; i++ {
+		start, end := cuts[i], cuts[i+1]
+		if end-start <= 0.01 {
+			continue
+		}
+		ranges = append(ranges, chunkRange{index: len(ranges), start: start, end: end})
+	}
+	return ranges
+}
+
+// snapToKeyframe returns the latest keyframe at or before target, or target
+// unchanged if no such keyframe was probed.
+func snapToKeyframe(target float64, keyframes []float64) float64 {
+	best := target
+	bestDelta := math.Inf(1)
+	found := false
+	for _, kf := range keyframes {
+		if kf > target {
+			continue
+		}
+		if delta := target - kf; delta < bestDelta {
+			bestDelta = delta
+			best = kf
+			found = true
+		}
+	}
+	if !found {
+		return target
+	}
+	return best
+}
+
+// localWatermarkWindows intersects wm's two absolute show-windows
+// ([0, StartDurationSeconds] and [EndTriggerSeconds, clipSeconds]) with
+// [chunkStart, chunkEnd), returning each overlap as chunk-local [start,end)
+// seconds suitable for a between(t,start,end) drawtext enable clause.
+func localWatermarkWindows(wm *watermarkConfig, chunkStart, chunkEnd, clipSeconds float64) [][2]float64 {
+	if wm == nil {
+		return nil
+	}
+
+	var windows [][2]float64
+	add := func(absStart, absEnd float64) {
+		start := math.Max(absStart, chunkStart) - chunkStart
+		end := math.Min(absEnd, chunkEnd) - chunkStart
+		if end > start {
+			windows = append(windows, [2]float64{start, end})
+		}
+	}
+	add(0, wm.StartDurationSeconds)
+	add(wm.EndTriggerSeconds, clipSeconds)
+	return windows
+}
@@ -0,0 +1,234 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+const (
+	encoderSoftware = "libx264"
+	// probeTimeout bounds the startup `ffmpeg -encoders`/`-hwaccels` probes
+	// detectEncoder runs, so a hung or misbehaving ffmpeg binary can't stall
+	// worker startup.
+	probeTimeout = 5 * time.Second
+)
+
+// knownHWEncoders is the hardware encoder preference order: the first one
+// both ffmpeg's encoder list and its hwaccel list report as available wins.
+// libx264 is always the fallback and is never probed for.
+var knownHWEncoders = []string{"h264_vaapi", "h264_nvenc", "h264_qsv"}
+
+// hwAccelForEncoder maps a hardware -c:v name to the -hwaccels entry that
+// must also be present for it to actually work, not just be compiled in.
+var hwAccelForEncoder = map[string]string{
+	"h264_vaapi": "vaapi",
+	"h264_nvenc": "cuda",
+	"h264_qsv":   "qsv",
+}
+
+// encoderProfile captures what differs between the software encoder and a
+// hardware one: the codec name, any ffmpeg arguments needed before -i, and
+// whether the filter graph needs an upload node before encoding.
+type encoderProfile struct {
+	Name       string
+	Hardware   bool
+	GlobalArgs []string
+
+	// SupportsTwoPass marks an encoder as able to run ffmpeg's classic
+	// -pass 1/-pass 2 workflow. Only libx264 does in this processor; a
+	// two-pass request against a hardware encoder falls back to a single
+	// capped-bitrate pass instead (see (encoderProfile).singleEncodeArgsForStrategy).
+	SupportsTwoPass bool
+}
+
+var encoderProfiles = map[string]encoderProfile{
+	encoderSoftware: {Name: encoderSoftware, SupportsTwoPass: true},
+	"h264_vaapi":    {Name: "h264_vaapi", Hardware: true, GlobalArgs: []string{"-vaapi_device", "/dev/dri/renderD128"}},
+	"h264_nvenc":    {Name: "h264_nvenc", Hardware: true},
+	"h264_qsv":      {Name: "h264_qsv", Hardware: true},
+}
+
+// hwUploadFilter returns the filter-graph node, if any, that must run on
+// inLabel before encoder e can read it, writing outLabel. VAAPI's encoder
+// only accepts frames already uploaded to a VAAPI surface; NVENC and QSV
+// accept system-memory frames directly, so they return "".
+func (e encoderProfile) hwUploadFilter(inLabel, outLabel string) string {
+	if e.Name == "h264_vaapi" {
+		return fmt.Sprintf("[%s]format=nv12,hwupload[%s]", inLabel, outLabel)
+	}
+	return ""
+}
+
+// singleEncodeArgs returns the -c:v and rate-control flags for a single
+// progressive-file encode (no bitrate ladder, one output stream).
+func (e encoderProfile) singleEncodeArgs() []string {
+	if args := e.hardwareBitrateArgs(defaultVideoBitrateKbps); args != nil {
+		return args
+	}
+	return []string{"-c:v", e.Name, "-preset", "veryfast", "-pix_fmt", "yuv420p"}
+}
+
+// hardwareBitrateArgs returns e's -c:v and bitrate-mode flags at
+// bitrateKbps, or nil if e isn't one of the known hardware encoders (i.e.
+// it's libx264, whose flags each caller builds itself).
+func (e encoderProfile) hardwareBitrateArgs(bitrateKbps int) []string {
+	bitrate := fmt.Sprintf("%dk", bitrateKbps)
+	switch e.Name {
+	case "h264_vaapi":
+		return []string{"-c:v", e.Name, "-b:v", bitrate, "-rc_mode", "CBR"}
+	case "h264_nvenc":
+		return []string{"-c:v", e.Name, "-b:v", bitrate, "-preset", "p4", "-rc", "vbr"}
+	case "h264_qsv":
+		return []string{"-c:v", e.Name, "-b:v", bitrate, "-preset", "veryfast"}
+	default:
+		return nil
+	}
+}
+
+// singleEncodeArgsForStrategy returns this encoder's -c:v and rate-control
+// flags for the single-file path under strategy, for every mode except
+// EncodingTwoPassBitrate on an encoder with SupportsTwoPass, which instead
+// needs two invocations built via twoPassArgs.
+func (e encoderProfile) singleEncodeArgsForStrategy(strategy ports.EncodingStrategy) []string {
+	switch strategy.Mode {
+	case ports.EncodingCappedCRF:
+		return e.cappedCRFArgs(strategy.CRF, strategy.MaxBitrateKbps)
+	case ports.EncodingTwoPassBitrate:
+		// Reached only when SupportsTwoPass is false: approximate the
+		// bitrate target with a single capped pass instead of ffmpeg's
+		// -pass log workflow, which hardware encoders don't support here.
+		bitrate := strategy.TargetKbps
+		if bitrate <= 0 {
+			bitrate = defaultVideoBitrateKbps
+		}
+		if args := e.hardwareBitrateArgs(bitrate); args != nil {
+			return args
+		}
+		args := []string{"-c:v", e.Name, "-b:v", fmt.Sprintf("%dk", bitrate)}
+		if strategy.MaxKbps > bitrate {
+			args = append(args, "-maxrate", fmt.Sprintf("%dk", strategy.MaxKbps), "-bufsize", fmt.Sprintf("%dk", strategy.MaxKbps*2))
+		}
+		return append(args, "-preset", "veryfast", "-pix_fmt", "yuv420p")
+	default:
+		args := e.singleEncodeArgs()
+		if strategy.CRF > 0 && e.Name == encoderSoftware {
+			args = append(args, "-crf", strconv.Itoa(strategy.CRF))
+		}
+		return args
+	}
+}
+
+// cappedCRFArgs returns the EncodingCappedCRF rate-control flags: libx264
+// gets a real -crf plus a -maxrate/-bufsize ceiling; a hardware encoder,
+// which has no CRF-equivalent in this processor's setup, falls back to
+// treating maxBitrateKbps as its target bitrate.
+func (e encoderProfile) cappedCRFArgs(crf, maxBitrateKbps int) []string {
+	if crf <= 0 {
+		crf = 23
+	}
+	if e.Name == encoderSoftware {
+		args := []string{"-c:v", e.Name, "-preset", "veryfast", "-pix_fmt", "yuv420p", "-crf", strconv.Itoa(crf)}
+		if maxBitrateKbps > 0 {
+			args = append(args, "-maxrate", fmt.Sprintf("%dk", maxBitrateKbps), "-bufsize", fmt.Sprintf("%dk", maxBitrateKbps*2))
+		}
+		return args
+	}
+	bitrate := maxBitrateKbps
+	if bitrate <= 0 {
+		bitrate = defaultVideoBitrateKbps
+	}
+	return e.hardwareBitrateArgs(bitrate)
+}
+
+// twoPassArgs returns the -c:v and bitrate flags for one pass of
+// EncodingTwoPassBitrate on an encoder with SupportsTwoPass. pass is 1 or
+// 2; passLogFile is ffmpeg's -passlogfile prefix, shared across both
+// passes so the second reads what the first measured.
+func (e encoderProfile) twoPassArgs(targetKbps, maxKbps, pass int, passLogFile string) []string {
+	bitrate := targetKbps
+	if bitrate <= 0 {
+		bitrate = defaultVideoBitrateKbps
+	}
+	args := []string{"-c:v", e.Name, "-b:v", fmt.Sprintf("%dk", bitrate)}
+	if maxKbps > bitrate {
+		args = append(args, "-maxrate", fmt.Sprintf("%dk", maxKbps), "-bufsize", fmt.Sprintf("%dk", maxKbps*2))
+	}
+	args = append(args, "-preset", "veryfast", "-pix_fmt", "yuv420p", "-pass", strconv.Itoa(pass), "-passlogfile", passLogFile)
+	return args
+}
+
+// renditionEncodeArgs returns the -c:v:index/rate-control flags for one
+// rendition of an ABR ladder at bitrateKbps.
+func (e encoderProfile) renditionEncodeArgs(index, bitrateKbps int) []string {
+	suffix := fmt.Sprintf(":%d", index)
+	bitrate := fmt.Sprintf("%dk", bitrateKbps)
+
+	switch e.Name {
+	case "h264_vaapi":
+		return []string{"-c:v" + suffix, e.Name, "-b:v" + suffix, bitrate, "-rc_mode", "CBR"}
+	case "h264_nvenc":
+		return []string{"-c:v" + suffix, e.Name, "-b:v" + suffix, bitrate, "-preset", "p4", "-rc", "vbr"}
+	case "h264_qsv":
+		return []string{"-c:v" + suffix, e.Name, "-b:v" + suffix, bitrate, "-preset", "veryfast"}
+	default:
+		return []string{"-c:v" + suffix, e.Name, "-b:v" + suffix, bitrate, "-preset", "veryfast", "-pix_fmt", "yuv420p"}
+	}
+}
+
+// detectEncoder picks which encoder new VideoProcessors default to. A
+// non-empty forceEncoder short-circuits detection (an operator override);
+// otherwise it probes the host via `ffmpeg -encoders`/`-hwaccels` and picks
+// the first available entry in knownHWEncoders, falling back to libx264.
+func detectEncoder(ffmpegPath, forceEncoder string, logger *zap.Logger) encoderProfile {
+	if forceEncoder != "" {
+		if profile, ok := encoderProfiles[forceEncoder]; ok {
+			logger.Info("ffmpeg processor: using forced encoder", zap.String("encoder", forceEncoder))
+			return profile
+		}
+		logger.Warn("ffmpeg processor: unknown forced encoder, falling back to auto-detection",
+			zap.String("encoder", forceEncoder))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	encoders := probeOutput(ctx, ffmpegPath, "-encoders")
+	hwaccels := probeOutput(ctx, ffmpegPath, "-hwaccels")
+
+	for _, name := range knownHWEncoders {
+		if strings.Contains(encoders, name) && strings.Contains(hwaccels, hwAccelForEncoder[name]) {
+			logger.Info("ffmpeg processor: selected hardware encoder", zap.String("encoder", name))
+			return encoderProfiles[name]
+		}
+	}
+
+	logger.Info("ffmpeg processor: no usable hardware encoder found, using libx264")
+	return encoderProfiles[encoderSoftware]
+}
+
+func probeOutput(ctx context.Context, ffmpegPath, flag string) string {
+	cmd := exec.CommandContext(ctx, ffmpegPath, "-hide_banner", flag)
+	output, _ := cmd.CombinedOutput()
+	return string(output)
+}
+
+// detectVMAF reports whether this ffmpeg build has the libvmaf filter, so
+// NewVideoProcessor can skip scoring two-pass/capped-CRF encodes against a
+// quality budget when it isn't available instead of failing every request.
+func detectVMAF(ffmpegPath string, logger *zap.Logger) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	available := strings.Contains(probeOutput(ctx, ffmpegPath, "-filters"), "libvmaf")
+	if available {
+		logger.Info("ffmpeg processor: libvmaf available, will score quality-budget encodes")
+	}
+	return available
+}
@@ -10,8 +10,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/alejandro/video-worker/internal/core/ports"
@@ -19,20 +21,53 @@ import (
 )
 
 const (
-	defaultClipDuration    = 30 * time.Second
-	defaultWidth           = 1280
-	defaultHeight          = 720
-	curtainSegmentDuration = 2500 * time.Millisecond
+	defaultClipDuration     = 30 * time.Second
+	defaultWidth            = 1280
+	defaultHeight           = 720
+	curtainSegmentDuration  = 2500 * time.Millisecond
+	abrSegmentDuration      = 4 * time.Second
+	defaultVideoBitrateKbps = 1400
+	defaultAudioBitrateKbps = 128
 )
 
+// defaultRenditions is the ABR ladder used when a dash/hls request doesn't
+// specify one. It mirrors the portrait 720x1280 target ProcessVideoUseCase
+// asks for today, stepped down to two lower rungs.
+var defaultRenditions = []ports.Rendition{
+	{Name: "360p", Width: 360, Height: 640, VideoBitrateKbps: 800, AudioBitrateKbps: 96},
+	{Name: "540p", Width: 540, Height: 960, VideoBitrateKbps: 1400, AudioBitrateKbps: 128},
+	{Name: "720p", Width: 720, Height: 1280, VideoBitrateKbps: 2500, AudioBitrateKbps: 128},
+}
+
 type VideoProcessor struct {
 	ffmpegPath  string
 	ffprobePath string
 	tempDir     string
 	logger      *zap.Logger
+	metrics     ports.Metrics
+
+	// encoder is the auto-detected (or operator-forced) default video
+	// encoder, probed once at construction. A request can override it per
+	// call via VideoProcessingOptions.EncoderProfile.
+	encoder encoderProfile
+
+	// chunkPoolSize bounds how many chunks of a ChunkSeconds-driven encode
+	// run concurrently.
+	chunkPoolSize int
+
+	// vmafAvailable records whether this ffmpeg build has libvmaf, probed
+	// once at construction. A two-pass/capped-CRF encode only scores its
+	// output against source quality when this is true.
+	vmafAvailable bool
 }
 
-func NewVideoProcessor(ffmpegPath, ffprobePath, tempDir string, logger *zap.Logger) *VideoProcessor {
+// NewVideoProcessor builds a VideoProcessor and probes the host for a
+// usable hardware encoder. forceEncoder, if set (e.g. from
+// FFMPEG_FORCE_ENCODER), skips that probe and uses the named encoder
+// instead; an unrecognized value is logged and ignored. chunkPoolSize
+// bounds concurrency for the chunked pipeline (VideoProcessingOptions.
+// ChunkSeconds); zero or negative defaults to runtime.NumCPU().
+func NewVideoProcessor(ffmpegPath, ffprobePath, tempDir, forceEncoder string, chunkPoolSize int, logger *zap.Logger, metrics ports.Metrics) *VideoProcessor {
 	if ffmpegPath == "" {
 		ffmpegPath = "ffmpeg"
 	}
@@ -45,19 +80,48 @@ func NewVideoProcessor(ffmpegPath, ffprobePath, tempDir string, logger *zap.Logg
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if chunkPoolSize <= 0 {
+		chunkPoolSize = runtime.NumCPU()
+	}
 
 	return &VideoProcessor{
-		ffmpegPath:  ffmpegPath,
-		ffprobePath: ffprobePath,
-		tempDir:     tempDir,
-		logger:      logger,
+		ffmpegPath:    ffmpegPath,
+		ffprobePath:   ffprobePath,
+		tempDir:       tempDir,
+		logger:        logger,
+		metrics:       metrics,
+		encoder:       detectEncoder(ffmpegPath, forceEncoder, logger),
+		chunkPoolSize: chunkPoolSize,
+		vmafAvailable: detectVMAF(ffmpegPath, logger),
+	}
+}
+
+// resolveEncoder returns the encoder this request should use: opts'
+// EncoderProfile override if it names a known encoder, otherwise the
+// processor's auto-detected/forced default.
+func (p *VideoProcessor) resolveEncoder(opts ports.VideoProcessingOptions) encoderProfile {
+	if opts.EncoderProfile == "" {
+		return p.encoder
 	}
+	if profile, ok := encoderProfiles[opts.EncoderProfile]; ok {
+		return profile
+	}
+	p.logger.Warn("ffmpeg processor: unknown EncoderProfile override, using processor default",
+		zap.String("encoder_profile", opts.EncoderProfile))
+	return p.encoder
 }
 
+// ErrOutputFormatUnsupported is returned by Process when opts.OutputFormat
+// is none of the values this processor knows how to produce.
+var ErrOutputFormatUnsupported = errors.New("ffmpeg processor: output format not yet supported")
+
 func (p *VideoProcessor) Process(ctx context.Context, input io.Reader, opts ports.VideoProcessingOptions) (*ports.ProcessedVideo, error) {
 	if input == nil {
 		return nil, errors.New("ffmpeg processor: input reader is nil")
 	}
+	if err := validateOutputFormat(opts); err != nil {
+		return nil, err
+	}
 
 	inputFile, err := os.CreateTemp(p.tempDir, "ffmpeg-input-*.mp4")
 	if err != nil {
@@ -76,43 +140,52 @@ func (p *VideoProcessor) Process(ctx context.Context, input io.Reader, opts port
 		return nil, fmt.Errorf("ffmpeg processor: close temp input: %w", err)
 	}
 
-	duration, err := p.probeDuration(ctx, inputPath)
-	if err != nil {
-		p.logger.Warn("ffmpeg processor: probe duration failed", zap.Error(err))
-	}
+	return p.processPath(ctx, inputPath, opts)
+}
 
-	clipDuration := opts.ClipDuration
-	if clipDuration <= 0 {
-		clipDuration = defaultClipDuration
-	}
-	if duration > 0 && (clipDuration > duration || clipDuration == 0) {
-		clipDuration = duration
-	}
-	if clipDuration <= 0 {
-		clipDuration = defaultClipDuration
+// ProcessPath behaves like Process but reads directly from an existing file
+// on disk, skipping the copy into a fresh temp input file. Callers use this
+// when the source video is already spooled to disk (see
+// internal/adapters/storage/spool) to avoid paying for that copy twice.
+func (p *VideoProcessor) ProcessPath(ctx context.Context, inputPath string, opts ports.VideoProcessingOptions) (*ports.ProcessedVideo, error) {
+	if err := validateOutputFormat(opts); err != nil {
+		return nil, err
 	}
+	return p.processPath(ctx, inputPath, opts)
+}
 
-	width := opts.TargetWidth
-	height := opts.TargetHeight
-	if width <= 0 {
-		width = defaultWidth
-	}
-	if height <= 0 {
-		height = defaultHeight
+func validateOutputFormat(opts ports.VideoProcessingOptions) error {
+	switch opts.OutputFormat {
+	case "", ports.OutputFormatMP4, ports.OutputFormatDASH, ports.OutputFormatHLS:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrOutputFormatUnsupported, opts.OutputFormat)
 	}
+}
 
+// filterBuild is the shared "canvas" filter graph: scale/pad/watermark/
+// curtains composited at a fixed resolution and ending in the [vout] label.
+// Both the single-file mp4 path and the ABR path encode from the same
+// [vout], the latter via an additional split+per-rendition scale.
+type filterBuild struct {
+	filterParts    []string
+	contentSeconds float64
+	curtainSeconds float64
+	totalSeconds   float64
+	totalDuration  time.Duration
+	frameRate      string
+	// imagePath is set when the watermark has an image overlay; callers
+	// must add it as a second ffmpeg -i input (index 1) alongside the main
+	// input for the [1:v] references in filterParts to resolve.
+	imagePath string
+}
+
+func buildCompositeFilter(width, height int, frameRate string, clipDuration time.Duration, watermark *ports.WatermarkOptions) *filterBuild {
 	contentSeconds := clipDuration.Seconds()
 	curtainSeconds := curtainSegmentDuration.Seconds()
 	totalDuration := clipDuration + 2*curtainSegmentDuration
 	totalSeconds := totalDuration.Seconds()
 
-	frameRate := "30"
-	if rate, err := p.probeFrameRate(ctx, inputPath); err == nil && rate != "" {
-		frameRate = rate
-	} else if err != nil {
-		p.logger.Debug("ffmpeg processor: probe frame rate failed", zap.Error(err))
-	}
-
 	baseFilters := []string{
 		fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", width, height),
 		fmt.Sprintf("pad=%d:%d:(%d-iw)/2:(%d-ih)/2", width, height, width, height),
@@ -129,13 +202,14 @@ func (p *VideoProcessor) Process(ctx context.Context, input io.Reader, opts port
 	filterParts := []string{fmt.Sprintf("[0:v]%s[vbase]", strings.Join(baseFilters, ","))}
 
 	var watermarkCfg *watermarkConfig
-	if opts.Watermark != nil {
-		watermarkCfg = normalizeWatermark(opts.Watermark, contentSeconds)
+	if watermark != nil {
+		watermarkCfg = normalizeWatermark(watermark, contentSeconds)
 	}
 
 	mainLabel := "vbase"
 	if watermarkCfg != nil {
-		drawArgs := buildDrawTextArgs(watermarkCfg, true)
+		enableExpr := fmt.Sprintf("lte(t,%.3f)+gte(t,%.3f)", watermarkCfg.StartDurationSeconds, watermarkCfg.EndTriggerSeconds)
+		drawArgs := buildDrawTextArgs(watermarkCfg, enableExpr)
 		filterParts = append(filterParts, fmt.Sprintf("[%s]drawtext=%s[vmain]", mainLabel, drawArgs))
 		mainLabel = "vmain"
 	}
@@ -149,7 +223,7 @@ func (p *VideoProcessor) Process(ctx context.Context, input io.Reader, opts port
 	startLabel := "vcurtain_start_base"
 	endLabel := "vcurtain_end_base"
 	if watermarkCfg != nil {
-		curtainDrawArgs := buildDrawTextArgs(watermarkCfg, false)
+		curtainDrawArgs := buildDrawTextArgs(watermarkCfg, "")
 		filterParts = append(filterParts,
 			fmt.Sprintf("[%s]drawtext=%s[vcurtain_start]", startLabel, curtainDrawArgs),
 			fmt.Sprintf("[%s]drawtext=%s[vcurtain_end]", endLabel, curtainDrawArgs),
@@ -158,7 +232,94 @@ func (p *VideoProcessor) Process(ctx context.Context, input io.Reader, opts port
 		endLabel = "vcurtain_end"
 	}
 
+	if watermarkCfg != nil && watermarkCfg.ImagePath != "" {
+		enableExpr := fmt.Sprintf("lte(t,%.3f)+gte(t,%.3f)", watermarkCfg.StartDurationSeconds, watermarkCfg.EndTriggerSeconds)
+		filterParts = append(filterParts, imageOverlayNodes(watermarkCfg, mainLabel, "vmain_img", 1, enableExpr)...)
+		mainLabel = "vmain_img"
+		filterParts = append(filterParts, imageOverlayNodes(watermarkCfg, startLabel, "vcurtain_start_img", 1, "")...)
+		startLabel = "vcurtain_start_img"
+		filterParts = append(filterParts, imageOverlayNodes(watermarkCfg, endLabel, "vcurtain_end_img", 1, "")...)
+		endLabel = "vcurtain_end_img"
+	}
+
 	filterParts = append(filterParts, fmt.Sprintf("[%s][%s][%s]concat=n=3:v=1:a=0[vout]", startLabel, mainLabel, endLabel))
+
+	var imagePath string
+	if watermarkCfg != nil {
+		imagePath = watermarkCfg.ImagePath
+	}
+
+	return &filterBuild{
+		filterParts:    filterParts,
+		contentSeconds: contentSeconds,
+		curtainSeconds: curtainSeconds,
+		totalSeconds:   totalSeconds,
+		totalDuration:  totalDuration,
+		frameRate:      frameRate,
+		imagePath:      imagePath,
+	}
+}
+
+func (p *VideoProcessor) processPath(ctx context.Context, inputPath string, opts ports.VideoProcessingOptions) (*ports.ProcessedVideo, error) {
+	duration, err := p.probeDuration(ctx, inputPath)
+	if err != nil {
+		p.logger.Warn("ffmpeg processor: probe duration failed", zap.Error(err))
+	}
+
+	clipDuration := opts.ClipDuration
+	if clipDuration <= 0 {
+		clipDuration = defaultClipDuration
+	}
+	if duration > 0 && (clipDuration > duration || clipDuration == 0) {
+		clipDuration = duration
+	}
+	if clipDuration <= 0 {
+		clipDuration = defaultClipDuration
+	}
+
+	frameRate := "30"
+	if rate, err := p.probeFrameRate(ctx, inputPath); err == nil && rate != "" {
+		frameRate = rate
+	} else if err != nil {
+		p.logger.Debug("ffmpeg processor: probe frame rate failed", zap.Error(err))
+	}
+
+	encoder := p.resolveEncoder(opts)
+	p.metrics.IncEncoderUsed(encoder.Name)
+
+	switch opts.OutputFormat {
+	case ports.OutputFormatDASH, ports.OutputFormatHLS:
+		renditions := opts.Renditions
+		if len(renditions) == 0 {
+			renditions = defaultRenditions
+		}
+		canvasWidth, canvasHeight := canvasDimensions(renditions, opts)
+		build := buildCompositeFilter(canvasWidth, canvasHeight, frameRate, clipDuration, opts.Watermark)
+		return p.encodeABR(ctx, inputPath, opts, build, renditions, encoder)
+	default:
+		width := opts.TargetWidth
+		height := opts.TargetHeight
+		if width <= 0 {
+			width = defaultWidth
+		}
+		if height <= 0 {
+			height = defaultHeight
+		}
+		if opts.ChunkSeconds > 0 {
+			return p.encodeChunked(ctx, inputPath, opts, width, height, frameRate, clipDuration.Seconds(), encoder)
+		}
+		build := buildCompositeFilter(width, height, frameRate, clipDuration, opts.Watermark)
+		return p.encodeSingle(ctx, inputPath, opts, build, width, height, encoder)
+	}
+}
+
+func (p *VideoProcessor) encodeSingle(ctx context.Context, inputPath string, opts ports.VideoProcessingOptions, build *filterBuild, width, height int, encoder encoderProfile) (*ports.ProcessedVideo, error) {
+	filterParts := append([]string{}, build.filterParts...)
+	mapLabel := "vout"
+	if node := encoder.hwUploadFilter("vout", "venc"); node != "" {
+		filterParts = append(filterParts, node)
+		mapLabel = "venc"
+	}
 	filter := strings.Join(filterParts, ";")
 
 	outputExt := opts.TargetFormat
@@ -175,16 +336,60 @@ func (p *VideoProcessor) Process(ctx context.Context, input io.Reader, opts port
 		return nil, fmt.Errorf("ffmpeg processor: close temp output: %w", err)
 	}
 
-	args := []string{"-y", "-i", inputPath, "-filter_complex", filter, "-map", "[vout]"}
+	strategy := opts.Encoding
+	twoPass := strategy.Mode == ports.EncodingTwoPassBitrate && encoder.SupportsTwoPass
+
+	var passLogFile string
+	if twoPass {
+		prefix, cleanupPassLog, err := p.newPassLogPrefix()
+		if err != nil {
+			_ = os.Remove(outputPath)
+			return nil, err
+		}
+		defer cleanupPassLog()
+		passLogFile = prefix
+
+		pass1Args := []string{"-y"}
+		pass1Args = append(pass1Args, encoder.GlobalArgs...)
+		pass1Args = append(pass1Args, "-i", inputPath)
+		if build.imagePath != "" {
+			pass1Args = append(pass1Args, "-i", build.imagePath)
+		}
+		pass1Args = append(pass1Args, "-filter_complex", filter, "-map", fmt.Sprintf("[%s]", mapLabel))
+		pass1Args = append(pass1Args, encoder.twoPassArgs(strategy.TargetKbps, strategy.MaxKbps, 1, passLogFile)...)
+		pass1Args = append(pass1Args, "-an", "-f", "null", "/dev/null")
+
+		pass1Cmd := exec.CommandContext(ctx, p.ffmpegPath, pass1Args...)
+		var pass1Stderr bytes.Buffer
+		pass1Cmd.Stdout = io.Discard
+		pass1Cmd.Stderr = &pass1Stderr
+		if err := pass1Cmd.Run(); err != nil {
+			_ = os.Remove(outputPath)
+			return nil, fmt.Errorf("ffmpeg processor: two-pass first pass failed: %w: %s", err, pass1Stderr.String())
+		}
+	}
+
+	args := []string{"-y"}
+	args = append(args, encoder.GlobalArgs...)
+	args = append(args, "-i", inputPath)
+	if build.imagePath != "" {
+		args = append(args, "-i", build.imagePath)
+	}
+	args = append(args, "-filter_complex", filter, "-map", fmt.Sprintf("[%s]", mapLabel))
 
-	args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", "yuv420p", "-movflags", "+faststart")
+	if twoPass {
+		args = append(args, encoder.twoPassArgs(strategy.TargetKbps, strategy.MaxKbps, 2, passLogFile)...)
+	} else {
+		args = append(args, encoder.singleEncodeArgsForStrategy(strategy)...)
+	}
+	args = append(args, "-movflags", "+faststart")
 
 	if opts.RemoveAudio {
 		args = append(args, "-an")
 	}
 
-	if totalSeconds > 0 {
-		args = append(args, "-t", fmt.Sprintf("%.3f", totalSeconds))
+	if build.totalSeconds > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", build.totalSeconds))
 	}
 
 	args = append(args, outputPath)
@@ -199,29 +404,281 @@ func (p *VideoProcessor) Process(ctx context.Context, input io.Reader, opts port
 		return nil, fmt.Errorf("ffmpeg processor: processing failed: %w: %s", err, stderr.String())
 	}
 
+	var qualityMetadata map[string]string
+	if strategy.Mode == ports.EncodingTwoPassBitrate || strategy.Mode == ports.EncodingCappedCRF {
+		qualityMetadata = p.measureQuality(ctx, outputPath, inputPath, build, width, height)
+	}
+
+	var artifacts []ports.ProcessedArtifact
+	if opts.Thumbnails != nil {
+		artifacts, err = p.buildThumbnailArtifacts(ctx, outputPath, build.totalSeconds, build.frameRate, width, height, opts.Thumbnails)
+		if err != nil {
+			_ = os.Remove(outputPath)
+			return nil, fmt.Errorf("ffmpeg processor: thumbnail generation failed: %w", err)
+		}
+	}
+
 	reader, err := os.Open(outputPath)
 	if err != nil {
 		_ = os.Remove(outputPath)
+		for _, artifact := range artifacts {
+			_ = artifact.Reader.Close()
+		}
 		return nil, fmt.Errorf("ffmpeg processor: open output: %w", err)
 	}
 
 	metadata := map[string]string{
-		"clip_duration_seconds":   fmt.Sprintf("%.3f", contentSeconds),
-		"curtain_segment_seconds": fmt.Sprintf("%.3f", curtainSeconds),
-		"total_duration_seconds":  fmt.Sprintf("%.3f", totalSeconds),
-		"frame_rate":              frameRate,
+		"clip_duration_seconds":   fmt.Sprintf("%.3f", build.contentSeconds),
+		"curtain_segment_seconds": fmt.Sprintf("%.3f", build.curtainSeconds),
+		"total_duration_seconds":  fmt.Sprintf("%.3f", build.totalSeconds),
+		"frame_rate":              build.frameRate,
 		"target_width":            strconv.Itoa(width),
 		"target_height":           strconv.Itoa(height),
 	}
+	for k, v := range qualityMetadata {
+		metadata[k] = v
+	}
 
 	return &ports.ProcessedVideo{
-		Reader:   &tempFileReadCloser{File: reader, path: outputPath},
-		Format:   outputExt,
-		Duration: totalDuration,
+		Reader:    &tempFileReadCloser{File: reader, path: outputPath},
+		Format:    outputExt,
+		Duration:  build.totalDuration,
+		Metadata:  metadata,
+		Artifacts: artifacts,
+	}, nil
+}
+
+// canvasDimensions picks the composited working resolution for an ABR
+// ladder: the largest rung, so every lower rendition is a clean downscale
+// of the same watermark/curtain composite rather than a re-composite.
+func canvasDimensions(renditions []ports.Rendition, opts ports.VideoProcessingOptions) (int, int) {
+	width := opts.TargetWidth
+	height := opts.TargetHeight
+	for _, r := range renditions {
+		if r.Width > width {
+			width = r.Width
+		}
+		if r.Height > height {
+			height = r.Height
+		}
+	}
+	if width <= 0 {
+		width = defaultWidth
+	}
+	if height <= 0 {
+		height = defaultHeight
+	}
+	return width, height
+}
+
+// encodeABR splits build's composited [vout] into one branch per rendition,
+// scales each down, and encodes them - video and, unless RemoveAudio, a
+// per-rendition re-encoded audio leg - with a single ffmpeg invocation into
+// a DASH MPD or HLS master+variant playlist package. The OutputMode switch
+// and master playlist/manifest themselves were delivered by chunk0-2 and
+// chunk1-1; this function's audio-leg mapping is chunk2-1's addition on top
+// of that already-built pipeline, not a separate ABR implementation.
+func (p *VideoProcessor) encodeABR(ctx context.Context, inputPath string, opts ports.VideoProcessingOptions, build *filterBuild, renditions []ports.Rendition, encoder encoderProfile) (*ports.ProcessedVideo, error) {
+	outputDir, err := os.MkdirTemp(p.tempDir, "ffmpeg-abr-*")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg processor: create temp output dir: %w", err)
+	}
+	removeDir := true
+	defer func() {
+		if removeDir {
+			_ = os.RemoveAll(outputDir)
+		}
+	}()
+
+	filterParts := append([]string{}, build.filterParts...)
+	splitLabels := make([]string, len(renditions))
+	for i := range renditions {
+		splitLabels[i] = fmt.Sprintf("vsplit%d", i)
+	}
+	filterParts = append(filterParts, fmt.Sprintf("[vout]split=%d%s", len(renditions), labelRefs(splitLabels)))
+
+	var mapArgs []string
+	for i, r := range renditions {
+		scaledLabel := fmt.Sprintf("vr%d", i)
+		filterParts = append(filterParts, fmt.Sprintf("[%s]scale=%d:%d[%s]", splitLabels[i], r.Width, r.Height, scaledLabel))
+		mapLabel := scaledLabel
+		if node := encoder.hwUploadFilter(scaledLabel, scaledLabel+"enc"); node != "" {
+			filterParts = append(filterParts, node)
+			mapLabel = scaledLabel + "enc"
+		}
+		mapArgs = append(mapArgs, "-map", fmt.Sprintf("[%s]", mapLabel))
+		if !opts.RemoveAudio {
+			// "?" tolerates a source with no audio track instead of failing
+			// the whole encode; every rendition maps the same source audio,
+			// just re-encoded at its own bitrate below.
+			mapArgs = append(mapArgs, "-map", "0:a:0?")
+		}
+	}
+	filter := strings.Join(filterParts, ";")
+
+	args := []string{"-y"}
+	args = append(args, encoder.GlobalArgs...)
+	args = append(args, "-i", inputPath)
+	if build.imagePath != "" {
+		args = append(args, "-i", build.imagePath)
+	}
+	args = append(args, "-filter_complex", filter)
+	args = append(args, mapArgs...)
+
+	for i, r := range renditions {
+		bitrate := r.VideoBitrateKbps
+		if bitrate <= 0 {
+			bitrate = defaultVideoBitrateKbps
+		}
+		args = append(args, encoder.renditionEncodeArgs(i, bitrate)...)
+		if !opts.RemoveAudio {
+			audioBitrate := r.AudioBitrateKbps
+			if audioBitrate <= 0 {
+				audioBitrate = defaultAudioBitrateKbps
+			}
+			args = append(args,
+				fmt.Sprintf("-c:a:%d", i), "aac",
+				fmt.Sprintf("-b:a:%d", i), fmt.Sprintf("%dk", audioBitrate),
+			)
+		}
+	}
+
+	if build.totalSeconds > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", build.totalSeconds))
+	}
+
+	var manifestName string
+	switch opts.OutputFormat {
+	case ports.OutputFormatDASH:
+		manifestName = "manifest.mpd"
+		adaptationSets := "id=0,streams=v"
+		if !opts.RemoveAudio {
+			adaptationSets += " id=1,streams=a"
+		}
+		args = append(args,
+			"-seg_duration", fmt.Sprintf("%.0f", abrSegmentDuration.Seconds()),
+			"-use_template", "1",
+			"-use_timeline", "1",
+			"-init_seg_name", "init-$RepresentationID$.m4s",
+			"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+			"-adaptation_sets", adaptationSets,
+			"-f", "dash",
+			filepath.Join(outputDir, manifestName),
+		)
+	case ports.OutputFormatHLS:
+		manifestName = "master.m3u8"
+		varStreamMap := make([]string, len(renditions))
+		for i := range renditions {
+			if opts.RemoveAudio {
+				varStreamMap[i] = fmt.Sprintf("v:%d", i)
+			} else {
+				varStreamMap[i] = fmt.Sprintf("v:%d,a:%d", i, i)
+			}
+		}
+		args = append(args,
+			"-hls_time", fmt.Sprintf("%.0f", abrSegmentDuration.Seconds()),
+			"-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(outputDir, "stream_%v_%03d.ts"),
+			"-var_stream_map", strings.Join(varStreamMap, " "),
+			"-master_pl_name", manifestName,
+			"-f", "hls",
+			filepath.Join(outputDir, "stream_%v.m3u8"),
+		)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrOutputFormatUnsupported, opts.OutputFormat)
+	}
+
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg processor: abr processing failed: %w: %s", err, stderr.String())
+	}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg processor: read abr output dir: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("ffmpeg processor: abr encode produced no output files")
+	}
+
+	refs := int32(len(names))
+	var manifestReader io.ReadCloser
+	var segments []ports.ProcessedSegment
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(outputDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg processor: open abr file %s: %w", name, err)
+		}
+		wrapped := &dirCleanupReadCloser{File: f, dir: outputDir, refs: &refs}
+		if name == manifestName {
+			manifestReader = wrapped
+			continue
+		}
+		segments = append(segments, ports.ProcessedSegment{
+			Path:        name,
+			ContentType: segmentContentType(name),
+			Reader:      wrapped,
+		})
+	}
+	if manifestReader == nil {
+		return nil, fmt.Errorf("ffmpeg processor: manifest %s not produced", manifestName)
+	}
+
+	removeDir = false
+
+	metadata := map[string]string{
+		"clip_duration_seconds":  fmt.Sprintf("%.3f", build.contentSeconds),
+		"total_duration_seconds": fmt.Sprintf("%.3f", build.totalSeconds),
+		"frame_rate":             build.frameRate,
+		"rendition_count":        strconv.Itoa(len(renditions)),
+	}
+
+	return &ports.ProcessedVideo{
+		Format:   string(opts.OutputFormat),
+		Duration: build.totalDuration,
 		Metadata: metadata,
+		Bundle: &ports.ProcessedBundle{
+			ManifestPath:   manifestName,
+			ManifestReader: manifestReader,
+			Segments:       segments,
+		},
 	}, nil
 }
 
+func labelRefs(labels []string) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString("[")
+		b.WriteString(l)
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+func segmentContentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".mpd":
+		return "application/dash+xml"
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".m4s":
+		return "video/iso.segment"
+	case ".ts":
+		return "video/mp2t"
+	default:
+		return "application/octet-stream"
+	}
+}
+
 func (p *VideoProcessor) probeFrameRate(ctx context.Context, path string) (string, error) {
 	cmd := exec.CommandContext(ctx, p.ffprobePath, "-v", "error", "-select_streams", "v:0", "-show_entries", "stream=avg_frame_rate", "-of", "default=noprint_wrappers=1:nokey=1", path)
 	output, err := cmd.CombinedOutput()
@@ -321,6 +778,15 @@ func normalizeWatermark(opts *ports.WatermarkOptions, clipSeconds float64) *wate
 		position = ports.WatermarkBottomRight
 	}
 
+	imageScale := opts.ImageScale
+	if imageScale <= 0 {
+		imageScale = 0.15
+	}
+	imageOpacity := opts.ImageOpacity
+	if imageOpacity <= 0 || imageOpacity > 1 {
+		imageOpacity = 1
+	}
+
 	return &watermarkConfig{
 		Text:                 text,
 		FontFile:             opts.FontFile,
@@ -333,6 +799,9 @@ func normalizeWatermark(opts *ports.WatermarkOptions, clipSeconds float64) *wate
 		MarginY:              marginY,
 		StartDurationSeconds: start,
 		EndTriggerSeconds:    startTrigger,
+		ImagePath:            opts.ImagePath,
+		ImageScale:           imageScale,
+		ImageOpacity:         imageOpacity,
 	}
 }
 
@@ -348,6 +817,58 @@ type watermarkConfig struct {
 	MarginY              int
 	StartDurationSeconds float64
 	EndTriggerSeconds    float64
+	ImagePath            string
+	ImageScale           float64
+	ImageOpacity         float64
+}
+
+// imageOverlayNodes returns the filter-graph nodes that scale/fade wm's
+// image watermark (read from ffmpeg input imgInputIndex) and overlay it
+// onto inLabel, writing outLabel. Returns nil if wm has no image overlay
+// configured. enableExpr, if non-empty, gates the overlay the same way it
+// gates buildDrawTextArgs' text.
+func imageOverlayNodes(wm *watermarkConfig, inLabel, outLabel string, imgInputIndex int, enableExpr string) []string {
+	if wm == nil || wm.ImagePath == "" {
+		return nil
+	}
+
+	logoFilters := []string{fmt.Sprintf("scale=iw*%.3f:-1", wm.ImageScale)}
+	if wm.ImageOpacity < 1 {
+		logoFilters = append(logoFilters, "format=rgba", fmt.Sprintf("colorchannelmixer=aa=%.3f", wm.ImageOpacity))
+	}
+	logoLabel := outLabel + "_logo"
+
+	x, y := overlayPositionExpressions(wm.Position, wm.MarginX, wm.MarginY)
+	overlayArgs := fmt.Sprintf("%s:%s", x, y)
+	if enableExpr != "" {
+		overlayArgs += fmt.Sprintf(":enable='%s'", enableExpr)
+	}
+
+	return []string{
+		fmt.Sprintf("[%d:v]%s[%s]", imgInputIndex, strings.Join(logoFilters, ","), logoLabel),
+		fmt.Sprintf("[%s][%s]overlay=%s[%s]", inLabel, logoLabel, overlayArgs, outLabel),
+	}
+}
+
+// overlayPositionExpressions mirrors positionExpressions' placement scheme
+// using overlay's main_w/main_h/overlay_w/overlay_h variables instead of
+// drawtext's text_w/text_h.
+func overlayPositionExpressions(pos ports.WatermarkPosition, marginX, marginY int) (string, string) {
+	mx := strconv.Itoa(marginX)
+	my := strconv.Itoa(marginY)
+
+	switch pos {
+	case ports.WatermarkTopLeft:
+		return mx, my
+	case ports.WatermarkTopRight:
+		return fmt.Sprintf("main_w-overlay_w-%s", mx), my
+	case ports.WatermarkBottomLeft:
+		return mx, fmt.Sprintf("main_h-overlay_h-%s", my)
+	case ports.WatermarkCenter:
+		return "(main_w-overlay_w)/2", "(main_h-overlay_h)/2"
+	default:
+		return fmt.Sprintf("main_w-overlay_w-%s", mx), fmt.Sprintf("main_h-overlay_h-%s", my)
+	}
 }
 
 func positionExpressions(pos ports.WatermarkPosition, marginX, marginY int) (string, string) {
@@ -377,7 +898,11 @@ func positionExpressions(pos ports.WatermarkPosition, marginX, marginY int) (str
 	return x, y
 }
 
-func buildDrawTextArgs(wm *watermarkConfig, includeEnable bool) string {
+// buildDrawTextArgs renders wm's drawtext filter arguments. enableExpr, if
+// non-empty, is used verbatim as the filter's enable='...' clause (e.g. an
+// lte/gte window against absolute clip time, or a between(t,..)+between(t,..)
+// expression against chunk-local time); empty means always-on.
+func buildDrawTextArgs(wm *watermarkConfig, enableExpr string) string {
 	if wm == nil {
 		return ""
 	}
@@ -401,8 +926,8 @@ func buildDrawTextArgs(wm *watermarkConfig, includeEnable bool) string {
 		fmt.Sprintf("x=%s", xExpr),
 		fmt.Sprintf("y=%s", yExpr),
 	)
-	if includeEnable {
-		drawArgs = append(drawArgs, fmt.Sprintf("enable='lte(t,%.3f)+gte(t,%.3f)'", wm.StartDurationSeconds, wm.EndTriggerSeconds))
+	if enableExpr != "" {
+		drawArgs = append(drawArgs, fmt.Sprintf("enable='%s'", enableExpr))
 	}
 
 	return strings.Join(drawArgs, ":")
@@ -449,3 +974,22 @@ func (t *tempFileReadCloser) Close() error {
 	}
 	return err
 }
+
+// dirCleanupReadCloser wraps one file of an ABR bundle that shares a temp
+// directory with its manifest and sibling segments. The directory is
+// removed once every sibling sharing the same refs counter has closed.
+type dirCleanupReadCloser struct {
+	*os.File
+	dir  string
+	refs *int32
+}
+
+func (d *dirCleanupReadCloser) Close() error {
+	err := d.File.Close()
+	if atomic.AddInt32(d.refs, -1) == 0 {
+		if removeErr := os.RemoveAll(d.dir); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
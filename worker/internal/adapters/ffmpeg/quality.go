@@ -0,0 +1,117 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// newPassLogPrefix reserves a unique path under tempDir for ffmpeg's
+// -passlogfile argument and returns a cleanup func that removes every
+// ffmpeg2pass-*.log* file ffmpeg writes alongside it, so the caller can
+// defer it right away and have two-pass logs cleaned up even on failure.
+func (p *VideoProcessor) newPassLogPrefix() (string, func(), error) {
+	f, err := os.CreateTemp(p.tempDir, "ffmpeg2pass-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("ffmpeg processor: create two-pass log prefix: %w", err)
+	}
+	prefix := f.Name()
+	_ = f.Close()
+	_ = os.Remove(prefix)
+
+	cleanup := func() {
+		matches, _ := filepath.Glob(prefix + "*")
+		for _, match := range matches {
+			_ = os.Remove(match)
+		}
+	}
+	return prefix, cleanup, nil
+}
+
+// measureQuality reports the final measured bitrate of a quality-budget
+// encode (EncodingTwoPassBitrate or EncodingCappedCRF) and, when libvmaf is
+// available, a VMAF score against the source, as metadata entries so
+// downstream code can flag under-quality outputs. Probe/score failures are
+// logged and omitted rather than failing the encode they're measuring.
+func (p *VideoProcessor) measureQuality(ctx context.Context, outputPath, inputPath string, build *filterBuild, width, height int) map[string]string {
+	metadata := map[string]string{}
+
+	if info, err := os.Stat(outputPath); err == nil && build.totalSeconds > 0 {
+		measuredKbps := float64(info.Size()) * 8 / 1000 / build.totalSeconds
+		metadata["measured_bitrate_kbps"] = fmt.Sprintf("%.0f", measuredKbps)
+	}
+
+	if p.vmafAvailable {
+		score, err := p.vmafScore(ctx, outputPath, inputPath, build, width, height)
+		if err != nil {
+			p.logger.Warn("ffmpeg processor: vmaf scoring failed, omitting from metadata")
+		} else {
+			metadata["vmaf_score"] = fmt.Sprintf("%.2f", score)
+		}
+	}
+
+	return metadata
+}
+
+// vmafScore compares outputPath's content range (between its two curtain
+// segments) against a reference built from inputPath by the same
+// scale/pad/trim chain buildCompositeFilter used, so the two sides share
+// resolution, SAR, and frame rate as libvmaf requires.
+func (p *VideoProcessor) vmafScore(ctx context.Context, outputPath, inputPath string, build *filterBuild, width, height int) (float64, error) {
+	logFile, err := os.CreateTemp(p.tempDir, "ffmpeg-vmaf-*.json")
+	if err != nil {
+		return 0, fmt.Errorf("create vmaf log file: %w", err)
+	}
+	logPath := logFile.Name()
+	_ = logFile.Close()
+	defer func() { _ = os.Remove(logPath) }()
+
+	refFilters := []string{
+		fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", width, height),
+		fmt.Sprintf("pad=%d:%d:(%d-iw)/2:(%d-ih)/2", width, height, width, height),
+		"setsar=1",
+		"format=yuv420p",
+	}
+	if build.frameRate != "" {
+		refFilters = append(refFilters, fmt.Sprintf("fps=%s", build.frameRate))
+	}
+	if build.contentSeconds > 0 {
+		refFilters = append(refFilters, fmt.Sprintf("trim=duration=%.3f", build.contentSeconds), "setpts=PTS-STARTPTS")
+	}
+
+	filter := fmt.Sprintf(
+		"[0:v]trim=start=%.3f:duration=%.3f,setpts=PTS-STARTPTS[dist];[1:v]%s[ref];[dist][ref]libvmaf=log_fmt=json:log_path=%s",
+		build.curtainSeconds, build.contentSeconds, strings.Join(refFilters, ","), escapeForFFMPEG(logPath),
+	)
+
+	args := []string{"-y", "-i", outputPath, "-i", inputPath, "-filter_complex", filter, "-f", "null", "-"}
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("ffmpeg processor: vmaf comparison failed: %w: %s", err, stderr.String())
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		return 0, fmt.Errorf("read vmaf log: %w", err)
+	}
+	var report struct {
+		PooledMetrics struct {
+			VMAF struct {
+				Mean float64 `json:"mean"`
+			} `json:"vmaf"`
+		} `json:"pooled_metrics"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return 0, fmt.Errorf("parse vmaf log: %w", err)
+	}
+	return report.PooledMetrics.VMAF.Mean, nil
+}
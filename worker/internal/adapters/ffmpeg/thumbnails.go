@@ -0,0 +1,210 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+const (
+	defaultThumbnailIntervalSeconds = 5.0
+	defaultThumbnailColumns         = 5
+	defaultThumbnailRows            = 5
+	defaultThumbnailWidth           = 160
+)
+
+type thumbnailConfig struct {
+	IntervalSeconds float64
+	Columns         int
+	Rows            int
+	Width           int
+}
+
+func normalizeThumbnails(opts *ports.ThumbnailOptions) thumbnailConfig {
+	cfg := thumbnailConfig{
+		IntervalSeconds: defaultThumbnailIntervalSeconds,
+		Columns:         defaultThumbnailColumns,
+		Rows:            defaultThumbnailRows,
+		Width:           defaultThumbnailWidth,
+	}
+	if opts == nil {
+		return cfg
+	}
+	if opts.IntervalSeconds > 0 {
+		cfg.IntervalSeconds = opts.IntervalSeconds
+	}
+	if opts.Columns > 0 {
+		cfg.Columns = opts.Columns
+	}
+	if opts.Rows > 0 {
+		cfg.Rows = opts.Rows
+	}
+	if opts.Width > 0 {
+		cfg.Width = opts.Width
+	}
+	return cfg
+}
+
+// buildThumbnailArtifacts samples sourcePath (the final composited output,
+// so its timeline already includes both curtain segments) into a JPEG
+// sprite sheet plus a matching WebVTT cue file. The VTT references the
+// sprite via ports.SpriteArtifactPlaceholder since its final uploaded name
+// isn't known until the use case assigns the processed video an ID.
+func (p *VideoProcessor) buildThumbnailArtifacts(ctx context.Context, sourcePath string, totalSeconds float64, frameRate string, canvasWidth, canvasHeight int, opts *ports.ThumbnailOptions) ([]ports.ProcessedArtifact, error) {
+	cfg := normalizeThumbnails(opts)
+
+	fps := parseFrameRateValue(frameRate)
+	if fps <= 0 {
+		fps = 30
+	}
+	frameStep := int(math.Round(cfg.IntervalSeconds * fps))
+	if frameStep < 1 {
+		frameStep = 1
+	}
+	tileHeight := cfg.Width
+	if canvasWidth > 0 {
+		tileHeight = int(math.Round(float64(cfg.Width) * float64(canvasHeight) / float64(canvasWidth)))
+	}
+
+	spriteFile, err := os.CreateTemp(p.tempDir, "ffmpeg-sprite-*.jpg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg processor: create temp sprite output: %w", err)
+	}
+	spritePath := spriteFile.Name()
+	if err := spriteFile.Close(); err != nil {
+		_ = os.Remove(spritePath)
+		return nil, fmt.Errorf("ffmpeg processor: close temp sprite output: %w", err)
+	}
+
+	filter := fmt.Sprintf("select='not(mod(n\\,%d))',scale=%d:-1,tile=%dx%d", frameStep, cfg.Width, cfg.Columns, cfg.Rows)
+	args := []string{
+		"-y",
+		"-i", sourcePath,
+		"-frames:v", "1",
+		"-vsync", "vfr",
+		"-an",
+		"-vf", filter,
+		spritePath,
+	}
+	cmd := exec.CommandContext(ctx, p.ffmpegPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(spritePath)
+		return nil, fmt.Errorf("ffmpeg processor: sprite sheet generation failed: %w: %s", err, stderr.String())
+	}
+
+	vtt := buildThumbnailVTT(cfg, tileHeight, totalSeconds)
+	vttFile, err := os.CreateTemp(p.tempDir, "ffmpeg-sprite-*.vtt")
+	if err != nil {
+		_ = os.Remove(spritePath)
+		return nil, fmt.Errorf("ffmpeg processor: create temp vtt output: %w", err)
+	}
+	vttPath := vttFile.Name()
+	if _, err := vttFile.WriteString(vtt); err != nil {
+		_ = vttFile.Close()
+		_ = os.Remove(spritePath)
+		_ = os.Remove(vttPath)
+		return nil, fmt.Errorf("ffmpeg processor: write vtt output: %w", err)
+	}
+	if err := vttFile.Close(); err != nil {
+		_ = os.Remove(spritePath)
+		_ = os.Remove(vttPath)
+		return nil, fmt.Errorf("ffmpeg processor: close temp vtt output: %w", err)
+	}
+
+	spriteReader, err := os.Open(spritePath)
+	if err != nil {
+		_ = os.Remove(spritePath)
+		_ = os.Remove(vttPath)
+		return nil, fmt.Errorf("ffmpeg processor: open sprite output: %w", err)
+	}
+	vttReader, err := os.Open(vttPath)
+	if err != nil {
+		_ = spriteReader.Close()
+		_ = os.Remove(vttPath)
+		return nil, fmt.Errorf("ffmpeg processor: open vtt output: %w", err)
+	}
+
+	return []ports.ProcessedArtifact{
+		{PathSuffix: ".sprite.jpg", ContentType: "image/jpeg", Reader: &tempFileReadCloser{File: spriteReader, path: spritePath}},
+		{PathSuffix: ".vtt", ContentType: "text/vtt", Reader: &tempFileReadCloser{File: vttReader, path: vttPath}},
+	}, nil
+}
+
+// buildThumbnailVTT renders one cue per sprite sheet tile, in row-major
+// tile order, clamped to totalSeconds so a short clip doesn't get cues for
+// tiles the sprite-sheet ffmpeg call never actually populated.
+func buildThumbnailVTT(cfg thumbnailConfig, tileHeight int, totalSeconds float64) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+
+	tileCount := cfg.Columns * cfg.Rows
+	for i := 0; i < tileCount; i++ {
+		start := float64(i) * cfg.IntervalSeconds
+		if start >= totalSeconds {
+			break
+		}
+		end := start + cfg.IntervalSeconds
+		if end > totalSeconds {
+			end = totalSeconds
+		}
+
+		col := i % cfg.Columns
+		row := i / cfg.Columns
+		x := col * cfg.Width
+		y := row * tileHeight
+
+		fmt.Fprintf(&b, "%s --> %s\n%s#xywh=%d,%d,%d,%d\n\n",
+			formatVTTTimestamp(start), formatVTTTimestamp(end), ports.SpriteArtifactPlaceholder, x, y, cfg.Width, tileHeight)
+	}
+
+	return b.String()
+}
+
+func formatVTTTimestamp(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	hours := total / time.Hour
+	total -= hours * time.Hour
+	minutes := total / time.Minute
+	total -= minutes * time.Minute
+	secs := total / time.Second
+	total -= secs * time.Second
+	millis := total / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, secs, millis)
+}
+
+// parseFrameRateValue converts an ffprobe-style frame rate ("30" or
+// "30000/1001") into a float, returning 0 if rate is empty or malformed.
+func parseFrameRateValue(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	if !found {
+		value, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return 0
+		}
+		return value
+	}
+	numValue, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	denValue, err := strconv.ParseFloat(den, 64)
+	if err != nil || denValue == 0 {
+		return 0
+	}
+	return numValue / denValue
+}
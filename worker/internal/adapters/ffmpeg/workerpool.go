@@ -0,0 +1,217 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// ErrPoolClosed is returned by Submit once the pool has stopped accepting
+// new jobs, either because Run's context was canceled or the pool was
+// otherwise drained.
+var ErrPoolClosed = errors.New("ffmpeg worker pool: closed")
+
+// Job describes a single transcode request submitted to a WorkerPool.
+// Exactly one of Input or InputPath should be set: InputPath lets the pool
+// use VideoProcessor.ProcessPath to read an already-spooled file directly,
+// skipping the temp-file copy Process performs for an arbitrary io.Reader.
+type Job struct {
+	Input     io.Reader
+	InputPath string
+	Opts      ports.VideoProcessingOptions
+}
+
+// Result carries the outcome of a Job processed by a WorkerPool.
+type Result struct {
+	Video *ports.ProcessedVideo
+	Err   error
+}
+
+type poolJob struct {
+	job    Job
+	result chan<- Result
+}
+
+// WorkerPool bounds the number of concurrently running ffmpeg/ffprobe
+// subprocesses to poolSize, regardless of how many queue consumers are
+// submitting work. Consumers call Submit, which blocks once the internal
+// job queue is saturated, decoupling queue fan-out from transcode
+// parallelism. WorkerPool also satisfies ports.VideoProcessor via Process,
+// so it can be handed to ProcessVideoUseCase as a drop-in, pool-backed
+// processor.
+type WorkerPool struct {
+	processor *VideoProcessor
+	poolSize  int
+	logger    *zap.Logger
+	metrics   ports.Metrics
+
+	jobs      chan poolJob
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mu         sync.Mutex
+	activeJobs int64
+}
+
+// NewWorkerPool creates a WorkerPool that drives processor with at most
+// poolSize concurrent ffmpeg invocations. queueSize bounds how many
+// submitted-but-not-yet-picked-up jobs may wait in Submit's channel before
+// callers block.
+func NewWorkerPool(processor *VideoProcessor, poolSize, queueSize int, logger *zap.Logger, metrics ports.Metrics) *WorkerPool {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	if queueSize <= 0 {
+		queueSize = poolSize
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &WorkerPool{
+		processor: processor,
+		poolSize:  poolSize,
+		logger:    logger,
+		metrics:   metrics,
+		jobs:      make(chan poolJob, queueSize),
+		closed:    make(chan struct{}),
+	}
+}
+
+// Run starts poolSize worker goroutines that pull jobs from the internal
+// queue and drive them through the underlying VideoProcessor. Run blocks
+// until ctx is canceled. In-flight jobs run under ctx, so they are canceled
+// with it; Run waits for each to deliver its result before it returns, and
+// any job still waiting in the queue receives ErrPoolClosed.
+func (p *WorkerPool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.poolSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.runWorker(ctx)
+		}()
+	}
+
+	<-ctx.Done()
+	p.closeOnce.Do(func() { close(p.closed) })
+	wg.Wait()
+	p.drainQueue()
+}
+
+func (p *WorkerPool) runWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case pj, ok := <-p.jobs:
+			if !ok {
+				return
+			}
+			p.setActive(1)
+			var video *ports.ProcessedVideo
+			var err error
+			if pj.job.InputPath != "" {
+				video, err = p.processor.ProcessPath(ctx, pj.job.InputPath, pj.job.Opts)
+			} else {
+				video, err = p.processor.Process(ctx, pj.job.Input, pj.job.Opts)
+			}
+			p.setActive(-1)
+			pj.result <- Result{Video: video, Err: err}
+			close(pj.result)
+		}
+	}
+}
+
+// drainQueue rejects any job left waiting after Run's context is canceled
+// so Submit callers don't block forever on shutdown.
+func (p *WorkerPool) drainQueue() {
+	for {
+		select {
+		case pj := <-p.jobs:
+			pj.result <- Result{Err: ErrPoolClosed}
+			close(pj.result)
+		default:
+			return
+		}
+	}
+}
+
+// Submit enqueues a job and returns a channel that receives its Result once
+// a worker picks it up and finishes processing. Submit blocks when the
+// queue is saturated, which is what caps the number of live ffmpeg
+// subprocesses: slow consumers simply wait here instead of spawning more
+// work than the pool can run.
+func (p *WorkerPool) Submit(ctx context.Context, job Job) (<-chan Result, error) {
+	select {
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	default:
+	}
+
+	result := make(chan Result, 1)
+	if p.metrics != nil {
+		p.metrics.SetFFmpegQueueDepth(int64(len(p.jobs)))
+	}
+
+	select {
+	case p.jobs <- poolJob{job: job, result: result}:
+		if p.metrics != nil {
+			p.metrics.SetFFmpegQueueDepth(int64(len(p.jobs)))
+		}
+		return result, nil
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, fmt.Errorf("ffmpeg worker pool: submit: %w", ctx.Err())
+	}
+}
+
+// Process submits input/opts to the pool and blocks for the result,
+// satisfying ports.VideoProcessor so the pool can be used anywhere a plain
+// *VideoProcessor is expected.
+func (p *WorkerPool) Process(ctx context.Context, input io.Reader, opts ports.VideoProcessingOptions) (*ports.ProcessedVideo, error) {
+	resultCh, err := p.Submit(ctx, Job{Input: input, Opts: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-resultCh:
+		return result.Video, result.Err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("ffmpeg worker pool: process: %w", ctx.Err())
+	}
+}
+
+// ProcessPath submits inputPath/opts to the pool and blocks for the result,
+// satisfying ports.PathAwareVideoProcessor so the pool can skip the
+// temp-file copy for callers that already have a spooled input file.
+func (p *WorkerPool) ProcessPath(ctx context.Context, inputPath string, opts ports.VideoProcessingOptions) (*ports.ProcessedVideo, error) {
+	resultCh, err := p.Submit(ctx, Job{InputPath: inputPath, Opts: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-resultCh:
+		return result.Video, result.Err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("ffmpeg worker pool: process path: %w", ctx.Err())
+	}
+}
+
+func (p *WorkerPool) setActive(delta int64) {
+	p.mu.Lock()
+	p.activeJobs += delta
+	active := p.activeJobs
+	p.mu.Unlock()
+	if p.metrics != nil {
+		p.metrics.SetFFmpegActiveWorkers(active)
+	}
+}
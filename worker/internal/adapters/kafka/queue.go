@@ -0,0 +1,351 @@
+// Package kafka adapts a Kafka topic to ports.MessageQueue using
+// segmentio/kafka-go, with per-partition offset commits standing in for
+// the ack semantics the Redis and SQS adapters get natively.
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/core/domain"
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// fieldNotBefore is the wire field Fail stamps onto a message it publishes
+// to retryTopic: the Unix millisecond timestamp RunRetryScheduler must wait
+// out before promoting it back onto the main topic. It's stripped before
+// the message is republished.
+const fieldNotBefore = "not_before"
+
+// Queue consumes from a single Kafka topic as part of a consumer group,
+// fetching messages without auto-committing so Ack/Fail fully control when
+// an offset becomes visible to the rest of the group.
+type Queue struct {
+	reader        *kafkago.Reader
+	writer        *kafkago.Writer
+	dlqTopic      string
+	dlqWriter     *kafkago.Writer
+	retryTopic    string
+	retryWriter   *kafkago.Writer
+	retryReader   *kafkago.Reader
+	maxDeliveries int
+	retryPolicy   ports.RetryPolicy
+	logger        *zap.Logger
+	metrics       ports.Metrics
+
+	inFlight map[string]kafkago.Message
+}
+
+// NewQueue builds a Kafka-backed MessageQueue consuming topic as part of
+// groupID.
+//
+// dlqTopic receives tasks that exhaust maxDeliveries with failure metadata
+// instead of them being silently discarded; empty defaults to
+// topic + ":dlq". retryTopic holds a failed task until its backoff delay
+// elapses so Fail doesn't republish straight back onto topic and hot-loop;
+// empty defaults to topic + ":retry". retryTopic is consumed by its own
+// consumer group (groupID + "-retry"), so multiple worker instances split
+// promotion work across its partitions the same way they already split
+// topic consumption, instead of electing a single leader the way
+// redis.StreamQueue does. retryPolicy is optional: when nil, a
+// ports.ExponentialBackoff with maxDeliveries attempts, a 1s base, a 5m cap
+// and 1s of jitter is used.
+func NewQueue(brokers []string, topic, groupID, dlqTopic, retryTopic string, maxDeliveries int, retryPolicy ports.RetryPolicy, logger *zap.Logger, metrics ports.Metrics) *Queue {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if dlqTopic == "" {
+		dlqTopic = topic + ":dlq"
+	}
+	if retryTopic == "" {
+		retryTopic = topic + ":retry"
+	}
+	if retryPolicy == nil {
+		retryPolicy = ports.ExponentialBackoff{
+			Attempts: maxDeliveries,
+			Base:     time.Second,
+			Cap:      5 * time.Minute,
+			Jitter:   time.Second,
+		}
+	}
+
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+	})
+
+	retryReader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers: brokers,
+		Topic:   retryTopic,
+		GroupID: groupID + "-retry",
+	})
+
+	return &Queue{
+		reader: reader,
+		writer: &kafkago.Writer{
+			Addr:     kafkago.TCP(brokers...),
+			Topic:    topic,
+			Balancer: &kafkago.LeastBytes{},
+		},
+		dlqTopic: dlqTopic,
+		dlqWriter: &kafkago.Writer{
+			Addr:     kafkago.TCP(brokers...),
+			Topic:    dlqTopic,
+			Balancer: &kafkago.LeastBytes{},
+		},
+		retryTopic: retryTopic,
+		retryWriter: &kafkago.Writer{
+			Addr:     kafkago.TCP(brokers...),
+			Topic:    retryTopic,
+			Balancer: &kafkago.LeastBytes{},
+		},
+		retryReader:   retryReader,
+		maxDeliveries: maxDeliveries,
+		retryPolicy:   retryPolicy,
+		logger:        logger,
+		metrics:       metrics,
+		inFlight:      make(map[string]kafkago.Message),
+	}
+}
+
+func (q *Queue) Fetch(ctx context.Context) (*ports.QueueMessage, error) {
+	if q.metrics != nil {
+		q.metrics.SetQueueDepth(q.reader.Config().GroupID, "kafka", int64(q.reader.Lag()))
+	}
+
+	kmsg, err := q.reader.FetchMessage(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("fetch kafka message: %w", err)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(kmsg.Value, &body); err != nil {
+		q.logger.Error("failed to parse kafka message value", zap.Error(err), zap.Int("partition", kmsg.Partition), zap.Int64("offset", kmsg.Offset))
+		if commitErr := q.reader.CommitMessages(ctx, kmsg); commitErr != nil {
+			q.logger.Error("failed to commit unparseable kafka message", zap.Error(commitErr))
+		}
+		return nil, fmt.Errorf("parse kafka message value: %w", err)
+	}
+
+	id := fmt.Sprintf("%d-%d", kmsg.Partition, kmsg.Offset)
+	q.inFlight[id] = kmsg
+
+	return &ports.QueueMessage{
+		ID:   id,
+		Task: hydrateTask(body),
+		Raw:  body,
+	}, nil
+}
+
+func (q *Queue) Ack(ctx context.Context, msg *ports.QueueMessage) error {
+	if msg == nil {
+		return errors.New("queue message is nil")
+	}
+	kmsg, ok := q.inFlight[msg.ID]
+	if !ok {
+		return fmt.Errorf("kafka queue: no in-flight message for id %s", msg.ID)
+	}
+	delete(q.inFlight, msg.ID)
+	return q.reader.CommitMessages(ctx, kmsg)
+}
+
+// Fail commits the original offset (Kafka has no per-message requeue) and
+// either dead-letters the task via deadLetter once maxDeliveries is
+// exhausted, or publishes it to retryTopic stamped with the not-before
+// timestamp retryPolicy's backoff computes, so RunRetryScheduler republishes
+// it onto the main topic once that delay elapses instead of it hot-looping
+// straight back the way it used to.
+func (q *Queue) Fail(ctx context.Context, msg *ports.QueueMessage, reason error) error {
+	if msg == nil {
+		return errors.New("queue message is nil")
+	}
+	kmsg, ok := q.inFlight[msg.ID]
+	if !ok {
+		return fmt.Errorf("kafka queue: no in-flight message for id %s", msg.ID)
+	}
+	delete(q.inFlight, msg.ID)
+
+	if msg.Task.Attempt+1 >= q.retryPolicy.MaxAttempts() {
+		if err := q.deadLetter(ctx, msg, reason); err != nil {
+			return err
+		}
+		return q.reader.CommitMessages(ctx, kmsg)
+	}
+
+	body := map[string]any{
+		"task_id":     msg.Task.ID,
+		"video_id":    msg.Task.VideoID,
+		"source_path": msg.Task.SourcePath,
+		"attempt":     msg.Task.Attempt + 1,
+	}
+	if reason != nil {
+		body["error"] = reason.Error()
+	}
+	for k, v := range msg.Raw {
+		if _, exists := body[k]; !exists {
+			body[k] = v
+		}
+	}
+	body[fieldNotBefore] = time.Now().Add(q.retryPolicy.NextDelay(msg.Task.Attempt)).UnixMilli()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal delayed retry message: %w", err)
+	}
+
+	if err := q.retryWriter.WriteMessages(ctx, kafkago.Message{Value: payload}); err != nil {
+		return fmt.Errorf("write delayed retry message: %w", err)
+	}
+
+	return q.reader.CommitMessages(ctx, kmsg)
+}
+
+// deadLetter publishes msg to dlqTopic with failure metadata - attempts,
+// the last error, and when it was dead-lettered - mirroring the redis and
+// SQS adapters' dead-letter payloads, and increments the dead-lettered
+// counter. The original message is left for the caller to commit.
+func (q *Queue) deadLetter(ctx context.Context, msg *ports.QueueMessage, reason error) error {
+	body := map[string]any{
+		"task_id":     msg.Task.ID,
+		"video_id":    msg.Task.VideoID,
+		"source_path": msg.Task.SourcePath,
+		"attempts":    msg.Task.Attempt + 1,
+		"failed_at":   time.Now().UTC().Format(time.RFC3339),
+	}
+	if reason != nil {
+		body["last_error"] = reason.Error()
+	}
+	for k, v := range msg.Raw {
+		if _, exists := body[k]; !exists {
+			body[k] = v
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal dead letter message: %w", err)
+	}
+	if err := q.dlqWriter.WriteMessages(ctx, kafkago.Message{Value: payload}); err != nil {
+		return fmt.Errorf("write dead letter message: %w", err)
+	}
+
+	q.logger.Warn("dead-lettering task after exhausting retries",
+		zap.String("task_id", msg.Task.ID),
+		zap.Int("attempts", msg.Task.Attempt+1),
+		zap.Error(reason),
+	)
+	if q.metrics != nil {
+		q.metrics.IncTaskDeadLettered("kafka_max_deliveries")
+	}
+	return nil
+}
+
+// RunRetryScheduler consumes retryTopic until ctx is canceled, waiting out
+// each message's fieldNotBefore timestamp before republishing it onto the
+// main topic and committing its retryTopic offset. Since the retry consumer
+// group partitions retryTopic the same way the main consumer group
+// partitions topic, this only blocks promotion for messages sharing a
+// partition with whatever is currently waiting out its delay - an accepted
+// simplification given retry delays already grow with attempt.
+func (q *Queue) RunRetryScheduler(ctx context.Context) {
+	for {
+		kmsg, err := q.retryReader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return
+			}
+			q.logger.Error("fetch delayed retry message failed", zap.Error(err))
+			continue
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(kmsg.Value, &body); err != nil {
+			q.logger.Error("failed to parse delayed retry message", zap.Error(err))
+			if commitErr := q.retryReader.CommitMessages(ctx, kmsg); commitErr != nil {
+				q.logger.Error("failed to commit unparseable delayed retry message", zap.Error(commitErr))
+			}
+			continue
+		}
+
+		notBeforeMs, _ := strconv.ParseInt(fmt.Sprint(body[fieldNotBefore]), 10, 64)
+		delete(body, fieldNotBefore)
+
+		if wait := time.Until(time.UnixMilli(notBeforeMs)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		payload, err := json.Marshal(body)
+		if err != nil {
+			q.logger.Error("failed to re-marshal delayed retry message", zap.Error(err))
+			if commitErr := q.retryReader.CommitMessages(ctx, kmsg); commitErr != nil {
+				q.logger.Error("failed to commit unmarshalable delayed retry message", zap.Error(commitErr))
+			}
+			continue
+		}
+		if err := q.writer.WriteMessages(ctx, kafkago.Message{Value: payload}); err != nil {
+			q.logger.Error("failed to promote delayed retry onto main topic", zap.Error(err))
+			continue
+		}
+		if err := q.retryReader.CommitMessages(ctx, kmsg); err != nil {
+			q.logger.Error("failed to commit promoted delayed retry", zap.Error(err))
+		}
+	}
+}
+
+// Close releases the underlying reader and writer connections.
+func (q *Queue) Close() error {
+	var firstErr error
+	for _, closer := range []func() error{
+		q.reader.Close,
+		q.writer.Close,
+		q.retryReader.Close,
+		q.retryWriter.Close,
+		q.dlqWriter.Close,
+	} {
+		if err := closer(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func hydrateTask(body map[string]any) domain.Task {
+	task := domain.Task{Metadata: make(map[string]string)}
+
+	for key, value := range body {
+		strVal := fmt.Sprint(value)
+		switch key {
+		case "task_id":
+			task.ID = strVal
+		case "video_id":
+			task.VideoID = strVal
+		case "source_path":
+			task.SourcePath = strVal
+		case "attempt":
+			if attempt, err := strconv.Atoi(strVal); err == nil {
+				task.Attempt = attempt
+			}
+		default:
+			task.Metadata[key] = strVal
+		}
+	}
+
+	return task
+}
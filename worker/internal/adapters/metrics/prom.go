@@ -7,10 +7,25 @@ import (
 )
 
 type PrometheusMetrics struct {
-	processed   *prometheus.CounterVec
-	durations   *prometheus.HistogramVec
-	queueErrors *prometheus.CounterVec
-	streamSize  *prometheus.GaugeVec
+	processed         *prometheus.CounterVec
+	durations         *prometheus.HistogramVec
+	queueErrors       *prometheus.CounterVec
+	queueDepth        *prometheus.GaugeVec
+	ffmpegQueueDepth  prometheus.Gauge
+	ffmpegActive      prometheus.Gauge
+	s3PartsUploaded   prometheus.Counter
+	s3PartRetries     prometheus.Counter
+	s3UploadAborts    prometheus.Counter
+	s3PartsDownloaded prometheus.Counter
+	bytesTransferred  *prometheus.CounterVec
+	taskProgress      *prometheus.GaugeVec
+	presignErrors     prometheus.Counter
+	taskDeadLettered  *prometheus.CounterVec
+	encoderUsed       *prometheus.CounterVec
+	chunkDuration     prometheus.Histogram
+	messagesReclaimed *prometheus.CounterVec
+	dlqDepth          prometheus.Gauge
+	retryDelayedDepth prometheus.Gauge
 }
 
 func NewPrometheusMetrics(reg prometheus.Registerer) *PrometheusMetrics {
@@ -31,14 +46,93 @@ func NewPrometheusMetrics(reg prometheus.Registerer) *PrometheusMetrics {
 			Name:      "queue_errors_total",
 			Help:      "Número total de errores.",
 		}, []string{"worker_id"}),
-		streamSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: "video_worker",
-			Name:      "stream_size",
-			Help:      "Tamaño actual del stream de Redis.",
-		}, []string{"worker_id"}),
+			Name:      "queue_depth",
+			Help:      "Número de mensajes pendientes en la cola, por consumidor y backend.",
+		}, []string{"consumer", "backend"}),
+		ffmpegQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "video_worker",
+			Name:      "ffmpeg_pool_queue_depth",
+			Help:      "Número de trabajos de ffmpeg esperando en el worker pool compartido.",
+		}),
+		ffmpegActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "video_worker",
+			Name:      "ffmpeg_pool_active_workers",
+			Help:      "Número de procesos de ffmpeg/ffprobe actualmente en ejecución.",
+		}),
+		s3PartsUploaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "s3_multipart_parts_uploaded_total",
+			Help:      "Número total de partes subidas exitosamente en uploads multipart de S3.",
+		}),
+		s3PartRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "s3_multipart_part_retries_total",
+			Help:      "Número total de reintentos de subida de partes en uploads multipart de S3.",
+		}),
+		s3UploadAborts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "s3_multipart_uploads_aborted_total",
+			Help:      "Número total de uploads multipart de S3 abortados.",
+		}),
+		s3PartsDownloaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "s3_ranged_parts_downloaded_total",
+			Help:      "Número total de partes descargadas exitosamente en descargas por rangos de S3.",
+		}),
+		bytesTransferred: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "bytes_transferred_total",
+			Help:      "Número total de bytes transferidos por fase (download, transcode, upload).",
+		}, []string{"phase", "worker_id"}),
+		taskProgress: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "video_worker",
+			Name:      "task_progress_percent",
+			Help:      "Porcentaje de avance de la tarea actual por fase.",
+		}, []string{"task_id", "phase"}),
+		presignErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "presign_errors_total",
+			Help:      "Número total de errores al generar una URL prefirmada para el video procesado.",
+		}),
+		taskDeadLettered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "tasks_dead_lettered_total",
+			Help:      "Número total de tareas enviadas a la cola de mensajes fallidos, por motivo.",
+		}, []string{"reason"}),
+		encoderUsed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "encoder_used_total",
+			Help:      "Número total de trabajos procesados por cada backend de codificación de video.",
+		}, []string{"encoder"}),
+		chunkDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "video_worker",
+			Name:      "chunk_processing_seconds",
+			Help:      "Histograma de la duración de codificación de cada chunk del pipeline de procesamiento concurrente.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		messagesReclaimed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "video_worker",
+			Name:      "messages_reclaimed_total",
+			Help:      "Número total de mensajes reclamados de la lista de entradas pendientes de un consumidor caído.",
+		}, []string{"consumer"}),
+		dlqDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "video_worker",
+			Name:      "dlq_depth",
+			Help:      "Número actual de mensajes en la cola de mensajes fallidos (dead-letter).",
+		}),
+		retryDelayedDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "video_worker",
+			Name:      "retry_delayed_depth",
+			Help:      "Número actual de tareas fallidas esperando su backoff antes de ser reintentadas.",
+		}),
 	}
 
-	reg.MustRegister(m.processed, m.durations, m.queueErrors, m.streamSize)
+	reg.MustRegister(m.processed, m.durations, m.queueErrors, m.queueDepth, m.ffmpegQueueDepth, m.ffmpegActive,
+		m.s3PartsUploaded, m.s3PartRetries, m.s3UploadAborts, m.s3PartsDownloaded, m.bytesTransferred, m.taskProgress,
+		m.presignErrors, m.taskDeadLettered, m.encoderUsed, m.chunkDuration, m.messagesReclaimed, m.dlqDepth,
+		m.retryDelayedDepth)
 	return m
 }
 
@@ -54,6 +148,66 @@ func (m *PrometheusMetrics) ObserveProcessingDuration(status string, workerID st
 	m.durations.WithLabelValues(status, workerID).Observe(d.Seconds())
 }
 
-func (m *PrometheusMetrics) SetStreamSize(workerID string, size int64) {
-	m.streamSize.WithLabelValues(workerID).Set(float64(size))
+func (m *PrometheusMetrics) SetQueueDepth(consumer, backend string, depth int64) {
+	m.queueDepth.WithLabelValues(consumer, backend).Set(float64(depth))
+}
+
+func (m *PrometheusMetrics) SetFFmpegQueueDepth(depth int64) {
+	m.ffmpegQueueDepth.Set(float64(depth))
+}
+
+func (m *PrometheusMetrics) SetFFmpegActiveWorkers(count int64) {
+	m.ffmpegActive.Set(float64(count))
+}
+
+func (m *PrometheusMetrics) IncS3PartUploaded() {
+	m.s3PartsUploaded.Inc()
+}
+
+func (m *PrometheusMetrics) IncS3PartRetry() {
+	m.s3PartRetries.Inc()
+}
+
+func (m *PrometheusMetrics) IncS3UploadAborted() {
+	m.s3UploadAborts.Inc()
+}
+
+func (m *PrometheusMetrics) IncS3PartDownloaded() {
+	m.s3PartsDownloaded.Inc()
+}
+
+func (m *PrometheusMetrics) ObserveBytesTransferred(phase, workerID string, n int64) {
+	m.bytesTransferred.WithLabelValues(phase, workerID).Add(float64(n))
+}
+
+func (m *PrometheusMetrics) SetTaskProgress(taskID, phase string, pct float64) {
+	m.taskProgress.WithLabelValues(taskID, phase).Set(pct)
+}
+
+func (m *PrometheusMetrics) IncPresignErrors() {
+	m.presignErrors.Inc()
+}
+
+func (m *PrometheusMetrics) IncTaskDeadLettered(reason string) {
+	m.taskDeadLettered.WithLabelValues(reason).Inc()
+}
+
+func (m *PrometheusMetrics) IncEncoderUsed(encoder string) {
+	m.encoderUsed.WithLabelValues(encoder).Inc()
+}
+
+func (m *PrometheusMetrics) ObserveChunkDuration(d time.Duration) {
+	m.chunkDuration.Observe(d.Seconds())
+}
+
+func (m *PrometheusMetrics) IncMessagesReclaimed(consumer string, count int) {
+	m.messagesReclaimed.WithLabelValues(consumer).Add(float64(count))
+}
+
+func (m *PrometheusMetrics) SetDLQDepth(depth int64) {
+	m.dlqDepth.Set(float64(depth))
+}
+
+func (m *PrometheusMetrics) SetRetryDelayedDepth(depth int64) {
+	m.retryDelayedDepth.Set(float64(depth))
 }
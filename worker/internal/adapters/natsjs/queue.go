@@ -0,0 +1,226 @@
+// Package natsjs adapts a NATS JetStream stream/consumer pair to
+// ports.MessageQueue, mapping attempt tracking onto JetStream's own
+// delivery metadata instead of a task-level counter field.
+package natsjs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/core/domain"
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// fetchWait bounds how long a single Fetch call waits for a message before
+// looping back to check ctx.
+const fetchWait = 5 * time.Second
+
+// retryBaseDelay/retryMaxDelay bound the NakWithDelay backoff Fail applies
+// between redeliveries, mirroring the fixed defaults redis.StreamQueue used
+// before RetryBaseDelay/RetryMaxDelay became configurable.
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 5 * time.Minute
+)
+
+// Queue consumes from a single JetStream consumer, tracking in-flight
+// messages by subject+sequence so Ack/Fail can look the underlying
+// jetstream.Msg back up.
+type Queue struct {
+	nc            *nats.Conn
+	consumer      jetstream.Consumer
+	maxDeliveries int
+	logger        *zap.Logger
+	metrics       ports.Metrics
+
+	inFlight map[string]jetstream.Msg
+}
+
+// NewQueue connects to url and binds to consumerName on streamName,
+// creating the consumer if it doesn't already exist.
+func NewQueue(ctx context.Context, url, streamName, consumerName string, maxDeliveries int, logger *zap.Logger, metrics ports.Metrics) (*Queue, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("connect nats: %w", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("init jetstream context: %w", err)
+	}
+
+	stream, err := js.Stream(ctx, streamName)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("lookup jetstream stream %q: %w", streamName, err)
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:   consumerName,
+		AckPolicy: jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("create jetstream consumer %q: %w", consumerName, err)
+	}
+
+	return &Queue{
+		nc:            nc,
+		consumer:      consumer,
+		maxDeliveries: maxDeliveries,
+		logger:        logger,
+		metrics:       metrics,
+		inFlight:      make(map[string]jetstream.Msg),
+	}, nil
+}
+
+func (q *Queue) Fetch(ctx context.Context) (*ports.QueueMessage, error) {
+	if q.metrics != nil {
+		if info, err := q.consumer.Info(ctx); err == nil {
+			q.metrics.SetQueueDepth(info.Name, "nats", int64(info.NumPending))
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		batch, err := q.consumer.Fetch(1, jetstream.FetchMaxWait(fetchWait))
+		if err != nil {
+			return nil, fmt.Errorf("fetch jetstream batch: %w", err)
+		}
+
+		for msg := range batch.Messages() {
+			return q.hydrate(msg)
+		}
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			return nil, fmt.Errorf("fetch jetstream batch: %w", err)
+		}
+		// No message arrived within fetchWait; loop back and check ctx again.
+	}
+}
+
+func (q *Queue) hydrate(msg jetstream.Msg) (*ports.QueueMessage, error) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return nil, fmt.Errorf("read jetstream message metadata: %w", err)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(msg.Data(), &body); err != nil {
+		q.logger.Error("failed to parse jetstream message payload", zap.Error(err), zap.String("subject", msg.Subject()))
+		_ = msg.Term()
+		return nil, fmt.Errorf("parse jetstream message payload: %w", err)
+	}
+
+	id := fmt.Sprintf("%s-%d", msg.Subject(), meta.Sequence.Stream)
+	q.inFlight[id] = msg
+
+	task := hydrateTask(body)
+	task.Attempt = int(meta.NumDelivered) - 1
+
+	return &ports.QueueMessage{
+		ID:   id,
+		Task: task,
+		Raw:  body,
+	}, nil
+}
+
+func (q *Queue) Ack(ctx context.Context, msg *ports.QueueMessage) error {
+	if msg == nil {
+		return errors.New("queue message is nil")
+	}
+	nmsg, ok := q.inFlight[msg.ID]
+	if !ok {
+		return fmt.Errorf("natsjs queue: no in-flight message for id %s", msg.ID)
+	}
+	delete(q.inFlight, msg.ID)
+	return nmsg.Ack()
+}
+
+// Fail terminates the message (so JetStream stops redelivering it) once
+// maxDeliveries is reached, logging the outcome the way the other backends'
+// dead-letter paths do; otherwise it Naks with an exponential backoff delay
+// so JetStream redelivers it itself, using its own NumDelivered counter as
+// the attempt source instead of a task-level field.
+func (q *Queue) Fail(ctx context.Context, msg *ports.QueueMessage, reason error) error {
+	if msg == nil {
+		return errors.New("queue message is nil")
+	}
+	nmsg, ok := q.inFlight[msg.ID]
+	if !ok {
+		return fmt.Errorf("natsjs queue: no in-flight message for id %s", msg.ID)
+	}
+	delete(q.inFlight, msg.ID)
+
+	if q.maxDeliveries > 0 && msg.Task.Attempt+1 >= q.maxDeliveries {
+		q.logger.Warn("discarding message after max deliveries",
+			zap.String("task_id", msg.Task.ID),
+			zap.Int("attempt", msg.Task.Attempt+1),
+			zap.Error(reason),
+		)
+		if q.metrics != nil {
+			q.metrics.IncTaskDeadLettered("nats_max_deliveries")
+		}
+		return nmsg.Term()
+	}
+
+	return nmsg.NakWithDelay(retryDelay(msg.Task.Attempt))
+}
+
+// Close drains the underlying NATS connection, flushing any pending acks.
+func (q *Queue) Close() error {
+	return q.nc.Drain()
+}
+
+func retryDelay(attempt int) time.Duration {
+	shift := attempt
+	if shift > 32 {
+		shift = 32
+	}
+	delay := retryBaseDelay * time.Duration(int64(1)<<uint(shift))
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+func hydrateTask(body map[string]any) domain.Task {
+	task := domain.Task{Metadata: make(map[string]string)}
+
+	for key, value := range body {
+		strVal := fmt.Sprint(value)
+		switch key {
+		case "task_id":
+			task.ID = strVal
+		case "video_id":
+			task.VideoID = strVal
+		case "source_path":
+			task.SourcePath = strVal
+		case "attempt":
+			if attempt, err := strconv.Atoi(strVal); err == nil {
+				task.Attempt = attempt
+			}
+		default:
+			task.Metadata[key] = strVal
+		}
+	}
+
+	return task
+}
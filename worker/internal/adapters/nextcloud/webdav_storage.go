@@ -1,23 +1,28 @@
 package nextcloud
 
 import (
-	"bytes"
 	"context"
 	"io"
 	"net/url"
 	"path"
+	"time"
 
 	"github.com/studio-b12/gowebdav"
 	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/adapters/storage/spool"
+	"github.com/alejandro/video-worker/internal/core/ports"
 )
 
 type WebDAVStorage struct {
-	client *gowebdav.Client
-	root   string
-	logger *zap.Logger
+	client         *gowebdav.Client
+	root           string
+	logger         *zap.Logger
+	tempDir        string
+	spoolThreshold int64
 }
 
-func NewWebDAVStorage(baseURL, root, username, password string, logger *zap.Logger) (*WebDAVStorage, error) {
+func NewWebDAVStorage(baseURL, root, username, password string, tempDir string, spoolThreshold int64, logger *zap.Logger) (*WebDAVStorage, error) {
 	parsed, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
@@ -29,9 +34,11 @@ func NewWebDAVStorage(baseURL, root, username, password string, logger *zap.Logg
 	client := gowebdav.NewClient(parsed.String(), username, password)
 
 	return &WebDAVStorage{
-		client: client,
-		root:   root,
-		logger: logger,
+		client:         client,
+		root:           root,
+		logger:         logger,
+		tempDir:        tempDir,
+		spoolThreshold: spoolThreshold,
 	}, nil
 }
 
@@ -39,28 +46,24 @@ func (s *WebDAVStorage) Download(ctx context.Context, remotePath string) (io.Rea
 	fullPath := s.fullPath(remotePath)
 	s.logger.Debug("downloading from Nextcloud", zap.String("path", fullPath))
 
-	// Try ReadStream first and read all content immediately
 	stream, err := s.client.ReadStream(fullPath)
 	if err != nil {
 		s.logger.Error("failed to open stream from Nextcloud", zap.String("path", fullPath), zap.Error(err))
 		return nil, err
 	}
+	defer func() {
+		_ = stream.Close()
+	}()
 
-	// Read all content from the stream immediately before it gets closed
-	data, err := io.ReadAll(stream)
-	_ = stream.Close() // Close the original stream
-
+	reader, err := spool.SpoolThreshold(s.tempDir, "nextcloud-download-*", s.spoolThreshold, stream)
 	if err != nil {
 		s.logger.Error("failed to read stream data", zap.String("path", fullPath), zap.Error(err))
 		return nil, err
 	}
 
-	s.logger.Info("successfully downloaded file from Nextcloud",
-		zap.String("path", fullPath),
-		zap.Int("bytes", len(data)))
+	s.logger.Info("successfully downloaded file from Nextcloud", zap.String("path", fullPath))
 
-	// Wrap the byte slice in a ReadCloser
-	return io.NopCloser(bytes.NewReader(data)), nil
+	return reader, nil
 }
 
 func (s *WebDAVStorage) Upload(ctx context.Context, remotePath string, data io.Reader) error {
@@ -72,6 +75,12 @@ func (s *WebDAVStorage) Upload(ctx context.Context, remotePath string, data io.R
 	return s.client.WriteStream(fullPath, data, 0644)
 }
 
+// Presign is unsupported: gowebdav exposes no Nextcloud OCS share-link API,
+// so callers fall back to the raw WebDAV path.
+func (s *WebDAVStorage) Presign(ctx context.Context, remotePath string, op ports.PresignOp, ttl time.Duration) (string, error) {
+	return "", ports.ErrUnsupported
+}
+
 func (s *WebDAVStorage) fullPath(p string) string {
 	return path.Join(s.root, p)
 }
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// taskLeaseStatusClaimed/Completed are the values TASK_LEDGER.status takes.
+const (
+	taskLeaseStatusClaimed   = "claimed"
+	taskLeaseStatusCompleted = "completed"
+)
+
+// TaskLedger is the postgres-backed ports.TaskLedger: a row per task_id in
+// "TASK_LEDGER" (task_id PK, video_id, status, first_seen, completed_at,
+// result_hash, worker_id, lease_expires_at) that makes HandleNext safe
+// under redelivery from an at-least-once queue.
+type TaskLedger struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+func NewTaskLedger(db *sql.DB, logger *zap.Logger) *TaskLedger {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &TaskLedger{db: db, logger: logger}
+}
+
+// Claim upserts a claimed lease for taskID, taking it over if the existing
+// lease has expired. The UPDATE branch's WHERE clause is what makes this
+// safe under concurrent callers: only an expired, non-completed lease is
+// replaced, so RETURNING reports no row when the lease is still live or the
+// task already completed, and a lookup distinguishes the two.
+func (l *TaskLedger) Claim(ctx context.Context, taskID, videoID, workerID string, leaseTTL time.Duration) error {
+	if leaseTTL <= 0 {
+		leaseTTL = time.Minute
+	}
+
+	const upsert = `
+INSERT INTO "TASK_LEDGER" (task_id, video_id, status, first_seen, worker_id, lease_expires_at)
+VALUES ($1, $2, '` + taskLeaseStatusClaimed + `', now(), $3, now() + $4)
+ON CONFLICT (task_id) DO UPDATE
+SET worker_id = $3,
+    status = '` + taskLeaseStatusClaimed + `',
+    lease_expires_at = now() + $4
+WHERE "TASK_LEDGER".status <> '` + taskLeaseStatusCompleted + `' AND "TASK_LEDGER".lease_expires_at < now()
+RETURNING worker_id`
+
+	var owner string
+	err := l.db.QueryRowContext(ctx, upsert, taskID, videoID, workerID, leaseTTL).Scan(&owner)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	const lookup = `SELECT status, worker_id FROM "TASK_LEDGER" WHERE task_id = $1`
+	var status, heldBy string
+	if lookupErr := l.db.QueryRowContext(ctx, lookup, taskID).Scan(&status, &heldBy); lookupErr != nil {
+		return lookupErr
+	}
+	if status == taskLeaseStatusCompleted {
+		return ports.ErrAlreadyCompleted
+	}
+	return fmt.Errorf("%w: %s", ports.ErrLeaseHeld, heldBy)
+}
+
+// Complete marks taskID done so a later redelivery short-circuits in Claim.
+func (l *TaskLedger) Complete(ctx context.Context, taskID, resultHash string) error {
+	const stmt = `
+UPDATE "TASK_LEDGER"
+SET status = '` + taskLeaseStatusCompleted + `',
+    completed_at = now(),
+    result_hash = $2
+WHERE task_id = $1`
+
+	_, err := l.db.ExecContext(ctx, stmt, taskID, resultHash)
+	return err
+}
+
+// Release drops a held lease early, e.g. after a non-retryable failure,
+// so another worker doesn't have to wait out leaseTTL to pick up taskID.
+func (l *TaskLedger) Release(ctx context.Context, taskID, workerID string) error {
+	const stmt = `
+DELETE FROM "TASK_LEDGER"
+WHERE task_id = $1 AND worker_id = $2 AND status = '` + taskLeaseStatusClaimed + `'`
+
+	_, err := l.db.ExecContext(ctx, stmt, taskID, workerID)
+	return err
+}
+
+// Reap deletes claimed leases past their expiry, so a task whose worker
+// crashed before calling Release or Complete becomes claimable again
+// without waiting for another worker's Claim to look it up first.
+func (l *TaskLedger) Reap(ctx context.Context) (int64, error) {
+	const stmt = `DELETE FROM "TASK_LEDGER" WHERE status = '` + taskLeaseStatusClaimed + `' AND lease_expires_at < now()`
+
+	res, err := l.db.ExecContext(ctx, stmt)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunReaper calls Reap every interval until ctx is canceled, logging how
+// many stale leases were cleared on each pass.
+func (l *TaskLedger) RunReaper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := l.Reap(ctx)
+			if err != nil {
+				l.logger.Error("task ledger reaper failed", zap.Error(err))
+				continue
+			}
+			if n > 0 {
+				l.logger.Info("task ledger reaper expired stale leases", zap.Int64("count", n))
+			}
+		}
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/alejandro/video-worker/internal/core/domain"
+	"github.com/alejandro/video-worker/internal/core/ports"
 )
 
 type VideoRepository struct {
@@ -35,16 +37,18 @@ SELECT id,
        processed_at,
        original_url,
        processed_url,
+       processed_playback_url,
        votes
 FROM "VIDEO"
 WHERE id = $1`
 
 	video := &domain.Video{}
 	var (
-		status           string
-		processedVideoID sql.NullString
-		processedURL     sql.NullString
-		processedAt      sql.NullTime
+		status               string
+		processedVideoID     sql.NullString
+		processedURL         sql.NullString
+		processedPlaybackURL sql.NullString
+		processedAt          sql.NullTime
 	)
 
 	if err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -58,6 +62,7 @@ WHERE id = $1`
 		&processedAt,
 		&video.OriginalURL,
 		&processedURL,
+		&processedPlaybackURL,
 		&video.Votes,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -74,6 +79,10 @@ WHERE id = $1`
 		value := processedURL.String
 		video.ProcessedURL = &value
 	}
+	if processedPlaybackURL.Valid {
+		value := processedPlaybackURL.String
+		video.ProcessedPlaybackURL = &value
+	}
 	if processedAt.Valid {
 		value := processedAt.Time
 		video.ProcessedAt = &value
@@ -89,7 +98,8 @@ UPDATE "VIDEO"
 SET status = $2,
     processed_video_id = $3,
     processed_url = $4,
-    processed_at = $5
+    processed_playback_url = $5,
+    processed_at = $6
 WHERE id = $1`
 
 	processedVideoID := sql.NullString{}
@@ -102,15 +112,47 @@ WHERE id = $1`
 		processedURL = sql.NullString{String: *video.ProcessedURL, Valid: true}
 	}
 
+	processedPlaybackURL := sql.NullString{}
+	if video.ProcessedPlaybackURL != nil && *video.ProcessedPlaybackURL != "" {
+		processedPlaybackURL = sql.NullString{String: *video.ProcessedPlaybackURL, Valid: true}
+	}
+
 	processedAt := sql.NullTime{}
 	if video.ProcessedAt != nil {
 		processedAt = sql.NullTime{Time: *video.ProcessedAt, Valid: true}
 	}
 
-	_, err := r.db.ExecContext(ctx, stmt, video.ID, string(video.Status), processedVideoID, processedURL, processedAt)
+	_, err := r.db.ExecContext(ctx, stmt, video.ID, string(video.Status), processedVideoID, processedURL, processedPlaybackURL, processedAt)
 	return err
 }
 
+// RefreshProcessedURL re-signs the processed video's playback URL against
+// storage and persists it, for when a previously issued one has expired.
+// It returns ErrUnsupported (via storage.Presign) if the backend has no
+// signed-link mechanism, and an error if the video has no processed URL yet.
+func (r *VideoRepository) RefreshProcessedURL(ctx context.Context, storage ports.Storage, id string, ttl time.Duration) (string, error) {
+	video, err := r.FindByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if video.ProcessedURL == nil || *video.ProcessedURL == "" {
+		return "", fmt.Errorf("video %s has no processed URL to re-sign", id)
+	}
+
+	playbackURL, err := storage.Presign(ctx, *video.ProcessedURL, ports.PresignGet, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	const stmt = `UPDATE "VIDEO" SET processed_playback_url = $2 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, stmt, id, playbackURL); err != nil {
+		r.logger.Error("failed to persist refreshed playback URL", zap.String("video_id", id), zap.Error(err))
+		return "", err
+	}
+
+	return playbackURL, nil
+}
+
 func toVideoStatus(raw string) domain.VideoStatus {
 	switch domain.VideoStatus(raw) {
 	case domain.VideoStatusUploaded,
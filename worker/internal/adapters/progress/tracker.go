@@ -0,0 +1,102 @@
+// Package progress wraps io.Readers so long-running download/transcode/
+// upload phases can report byte-level progress without the caller threading
+// counters through manually.
+package progress
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// tickInterval and tickDeltaPct throttle how often a Tracker emits a
+// percentage update, so a fast transfer doesn't spam metrics or a
+// ProgressSink.
+const (
+	tickInterval = 500 * time.Millisecond
+	tickDeltaPct = 5.0
+)
+
+// Tracker accumulates bytes transferred during one phase (download,
+// transcode, upload) of a task and reports throttled progress via Metrics
+// and, if configured, a ProgressSink.
+type Tracker struct {
+	metrics  ports.Metrics
+	sink     ports.ProgressSink
+	taskID   string
+	phase    string
+	workerID string
+	expected int64 // total expected bytes for the phase, 0 if unknown
+
+	read     int64
+	lastTick time.Time
+	lastPct  float64
+}
+
+// NewTracker builds a Tracker for one phase of task taskID. expected is the
+// total number of bytes the phase is expected to move, or 0 if unknown; with
+// 0, byte counters still get reported but no percentage does until Done.
+func NewTracker(metrics ports.Metrics, sink ports.ProgressSink, taskID, phase, workerID string, expected int64) *Tracker {
+	return &Tracker{metrics: metrics, sink: sink, taskID: taskID, phase: phase, workerID: workerID, expected: expected}
+}
+
+// Reader wraps r so every successful Read is reported to the tracker.
+func (t *Tracker) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &progressReader{Reader: r, ctx: ctx, tracker: t}
+}
+
+// Done reports the phase as 100% complete. Call it once a phase finishes
+// successfully, since the expected byte count (if any) may not exactly
+// match what was actually read.
+func (t *Tracker) Done(ctx context.Context) {
+	t.emit(ctx, 100)
+}
+
+func (t *Tracker) observe(ctx context.Context, n int64) {
+	t.read += n
+	if t.metrics != nil {
+		t.metrics.ObserveBytesTransferred(t.phase, t.workerID, n)
+	}
+	if t.expected <= 0 {
+		return
+	}
+
+	pct := float64(t.read) / float64(t.expected) * 100
+	if pct > 100 {
+		pct = 100
+	}
+	now := time.Now()
+	if !t.lastTick.IsZero() && now.Sub(t.lastTick) < tickInterval && pct-t.lastPct < tickDeltaPct {
+		return
+	}
+	t.lastTick = now
+	t.emit(ctx, pct)
+}
+
+func (t *Tracker) emit(ctx context.Context, pct float64) {
+	t.lastPct = pct
+	if t.metrics != nil {
+		t.metrics.SetTaskProgress(t.taskID, t.phase, pct)
+	}
+	if t.sink != nil {
+		_ = t.sink.ReportProgress(ctx, t.taskID, t.phase, pct)
+	}
+}
+
+// progressReader wraps an io.Reader, reporting every successful Read to a
+// Tracker.
+type progressReader struct {
+	io.Reader
+	ctx     context.Context
+	tracker *Tracker
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.Reader.Read(buf)
+	if n > 0 {
+		p.tracker.observe(p.ctx, int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,86 @@
+// Package queue centralizes ports.MessageQueue construction so main.go
+// doesn't need its own per-backend switch statement.
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	redislib "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/adapters/config"
+	kafkaadapter "github.com/alejandro/video-worker/internal/adapters/kafka"
+	natsadapter "github.com/alejandro/video-worker/internal/adapters/natsjs"
+	redisadapter "github.com/alejandro/video-worker/internal/adapters/redis"
+	sqsadapter "github.com/alejandro/video-worker/internal/adapters/sqs"
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// Options carries the per-worker-goroutine inputs New needs that don't
+// belong on config.Config: a backend client the caller already owns the
+// lifecycle of (redis), and a consumer identity unique to this goroutine.
+type Options struct {
+	// RedisClient is required when cfg.QueueBackend is "redis". The caller
+	// owns connecting, pinging, and closing it.
+	RedisClient *redislib.Client
+	// ConsumerName identifies this worker goroutine to backends that track
+	// per-consumer state (redis consumer groups). NATS ignores it and uses
+	// the shared cfg.NATSConsumer durable instead.
+	ConsumerName string
+	Logger       *zap.Logger
+	Metrics      ports.Metrics
+}
+
+// New builds the ports.MessageQueue implementation selected by
+// cfg.QueueBackend.
+func New(ctx context.Context, cfg *config.Config, opts Options) (ports.MessageQueue, error) {
+	switch cfg.QueueBackend {
+	case "redis":
+		return redisadapter.NewStreamQueue(
+			ctx,
+			opts.RedisClient,
+			cfg.RedisStream,
+			cfg.RedisGroup,
+			opts.ConsumerName,
+			cfg.RedisBlockTimeout,
+			cfg.MaxDeliveries,
+			cfg.RedisClaimMinIdle,
+			cfg.RedisDLQStream,
+			cfg.RetryBaseDelay,
+			cfg.RetryMaxDelay,
+			cfg.RetryJitter,
+			opts.Logger,
+			opts.Metrics,
+		)
+	case "sqs":
+		retryPolicy := ports.ExponentialBackoff{
+			Attempts: cfg.MaxDeliveries,
+			Base:     cfg.SQSRetryBaseDelay,
+			Cap:      cfg.SQSRetryMaxDelay,
+			Jitter:   cfg.SQSRetryJitter,
+		}
+		return sqsadapter.NewSQSQueue(ctx, cfg.SQSQueueURL, cfg.SQSDeadLetterQueueURL, cfg.SQSRegion, cfg.MaxDeliveries, cfg.SQSWaitTime, retryPolicy, opts.Logger, opts.Metrics)
+	case "kafka":
+		// All workers share cfg.KafkaGroupID: unlike Redis Streams' named
+		// consumers, Kafka assigns each group member its own partitions
+		// automatically, so a shared group ID is what spreads work across
+		// workers instead of duplicating it.
+		retryPolicy := ports.ExponentialBackoff{
+			Attempts: cfg.MaxDeliveries,
+			Base:     cfg.KafkaRetryBaseDelay,
+			Cap:      cfg.KafkaRetryMaxDelay,
+			Jitter:   cfg.KafkaRetryJitter,
+		}
+		return kafkaadapter.NewQueue(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaGroupID, cfg.KafkaDLQTopic, cfg.KafkaRetryTopic, cfg.MaxDeliveries, retryPolicy, opts.Logger, opts.Metrics), nil
+	case "nats":
+		// All workers share cfg.NATSConsumer as the durable name: a durable
+		// JetStream consumer delivers each message to exactly one puller, so
+		// a shared durable is what spreads work across workers the way
+		// KafkaGroupID does above, instead of giving every worker its own
+		// durable that independently receives a full copy of the stream.
+		return natsadapter.NewQueue(ctx, cfg.NATSURL, cfg.NATSStream, cfg.NATSConsumer, cfg.MaxDeliveries, opts.Logger, opts.Metrics)
+	default:
+		return nil, fmt.Errorf("unsupported queue backend: %s", cfg.QueueBackend)
+	}
+}
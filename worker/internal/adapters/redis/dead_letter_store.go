@@ -0,0 +1,176 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	redislib "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/adapters/taskcodec"
+	"github.com/alejandro/video-worker/internal/core/domain"
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// dlqListPageSize is the default List page size when the caller passes
+// limit <= 0.
+const dlqListPageSize = 50
+
+// DeadLetterStore is the redis-backed ports.DeadLetterStore. It reads and
+// manages entries XADDed to dlqStream by StreamQueue.Fail and
+// handleReclaimed once a task exhausts maxDeliveries, and can replay one
+// back onto mainStream with its delivery attempts reset.
+type DeadLetterStore struct {
+	client     *redislib.Client
+	dlqStream  string
+	mainStream string
+	logger     *zap.Logger
+}
+
+func NewDeadLetterStore(client *redislib.Client, dlqStream, mainStream string, logger *zap.Logger) *DeadLetterStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &DeadLetterStore{client: client, dlqStream: dlqStream, mainStream: mainStream, logger: logger}
+}
+
+// List returns up to limit entries starting after cursor (an empty cursor
+// starts from the oldest entry), and the cursor to pass back in for the
+// next page, or "" once the dead-letter stream has been exhausted.
+func (s *DeadLetterStore) List(ctx context.Context, cursor string, limit int) ([]ports.DeadLetterEntry, string, error) {
+	if cursor == "" {
+		cursor = "-"
+	}
+	if limit <= 0 {
+		limit = dlqListPageSize
+	}
+
+	msgs, err := s.client.XRangeN(ctx, s.dlqStream, cursor, "+", int64(limit)).Result()
+	if err != nil {
+		return nil, "", fmt.Errorf("xrange dead letter stream: %w", err)
+	}
+
+	entries := make([]ports.DeadLetterEntry, 0, len(msgs))
+	for _, xmsg := range msgs {
+		entry, err := hydrateDeadLetterEntry(xmsg)
+		if err != nil {
+			s.logger.Warn("failed to decode dead letter entry",
+				zap.Error(err), zap.String("message_id", xmsg.ID))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	next := ""
+	if len(msgs) == limit {
+		next = "(" + msgs[len(msgs)-1].ID
+	}
+	return entries, next, nil
+}
+
+// Get fetches one dead-letter entry by its stream ID, or
+// ports.ErrDeadLetterNotFound if it isn't there (already requeued, purged,
+// or never existed).
+func (s *DeadLetterStore) Get(ctx context.Context, id string) (*ports.DeadLetterEntry, error) {
+	msgs, err := s.client.XRangeN(ctx, s.dlqStream, id, id, 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("xrange dead letter stream: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil, ports.ErrDeadLetterNotFound
+	}
+
+	entry, err := hydrateDeadLetterEntry(msgs[0])
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Requeue moves entry id back onto mainStream with Task.Attempt reset to
+// 0, giving the task a fresh set of delivery attempts, then removes it from
+// the dead-letter stream. The task is rebuilt from the entry's Raw snapshot
+// when that decodes, and from its flat task fields otherwise.
+func (s *DeadLetterStore) Requeue(ctx context.Context, id string) error {
+	entry, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	nextTask := entry.Task
+	if len(entry.Raw) > 0 {
+		if decoded, err := taskcodec.Decode(entry.Raw); err == nil {
+			nextTask = decoded
+		}
+	}
+	nextTask.Attempt = 0
+	values, err := taskcodec.Encode(nextTask)
+	if err != nil {
+		return fmt.Errorf("encode requeue payload: %w", err)
+	}
+
+	if _, err := s.client.XAdd(ctx, &redislib.XAddArgs{Stream: s.mainStream, Values: values}).Result(); err != nil {
+		return fmt.Errorf("requeue dead letter entry: %w", err)
+	}
+	return s.client.XDel(ctx, s.dlqStream, id).Err()
+}
+
+// Purge permanently deletes the given entries from the dead-letter stream
+// without requeuing them.
+func (s *DeadLetterStore) Purge(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.client.XDel(ctx, s.dlqStream, ids...).Err()
+}
+
+// hydrateDeadLetterEntry decodes one dead-letter stream message back into a
+// ports.DeadLetterEntry, unmarshaling the raw_json snapshot StreamQueue's
+// deadLetter wrote alongside the task's own fields.
+func hydrateDeadLetterEntry(xmsg redislib.XMessage) (ports.DeadLetterEntry, error) {
+	entry := ports.DeadLetterEntry{ID: xmsg.ID}
+	task := domain.Task{Metadata: make(map[string]string)}
+	var rawJSON string
+
+	for key, value := range xmsg.Values {
+		strVal := fmt.Sprint(value)
+		switch key {
+		case "task_id":
+			task.ID = strVal
+		case "video_id":
+			task.VideoID = strVal
+		case "source_path":
+			task.SourcePath = strVal
+		case "original_id":
+			entry.OriginalID = strVal
+		case "last_error":
+			entry.LastError = strVal
+		case "failed_at":
+			if t, err := time.Parse(time.RFC3339, strVal); err == nil {
+				entry.FailedAt = t
+			}
+		case "attempts":
+			if n, err := strconv.Atoi(strVal); err == nil {
+				entry.Attempts = n
+			}
+		case "raw_json":
+			rawJSON = strVal
+		default:
+			task.Metadata[key] = strVal
+		}
+	}
+	task.Attempt = entry.Attempts
+	entry.Task = task
+
+	if rawJSON != "" {
+		raw := make(map[string]any)
+		if err := json.Unmarshal([]byte(rawJSON), &raw); err != nil {
+			return entry, fmt.Errorf("unmarshal raw snapshot: %w", err)
+		}
+		entry.Raw = raw
+	}
+	return entry, nil
+}
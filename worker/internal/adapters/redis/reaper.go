@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	redislib "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/adapters/taskcodec"
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// reclaimPageSize bounds how many pending entries one XAUTOCLAIM call scans
+// per page; Reclaim follows the returned cursor to cover the rest.
+const reclaimPageSize = 50
+
+// RunReclaimer calls Reclaim every interval until ctx is canceled, so a
+// message stuck in the consumer group's Pending Entries List after a worker
+// crash doesn't stay there forever.
+func (q *StreamQueue) RunReclaimer(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := q.Reclaim(ctx)
+			if err != nil {
+				q.logger.Error("stream queue reclaim failed", zap.Error(err))
+				continue
+			}
+			if n > 0 {
+				q.logger.Info("stream queue reclaimed idle pending messages", zap.Int("count", n))
+			}
+		}
+	}
+}
+
+// Reclaim pages through the consumer group's Pending Entries List via
+// XAUTOCLAIM, following the cursor it returns until the whole PEL has been
+// scanned rather than stopping after one batch, and claims every entry idle
+// for at least minIdleTime to this queue's consumer. Each claimed message
+// either gets dead-lettered (maxDeliveries reached) or re-queued with
+// Task.Attempt incremented via a fresh XADD, to be picked up by whichever
+// consumer's XReadGroup reads it next. Returns how many claimed messages
+// were successfully dead-lettered or re-queued.
+func (q *StreamQueue) Reclaim(ctx context.Context) (int, error) {
+	reclaimed := 0
+	cursor := "0-0"
+	for {
+		entries, next, err := q.client.XAutoClaim(ctx, &redislib.XAutoClaimArgs{
+			Stream:   q.stream,
+			Group:    q.group,
+			Consumer: q.consumer,
+			MinIdle:  q.minIdleTime,
+			Start:    cursor,
+			Count:    reclaimPageSize,
+		}).Result()
+		if err != nil {
+			return reclaimed, fmt.Errorf("xautoclaim: %w", err)
+		}
+
+		for _, xmsg := range entries {
+			if err := q.handleReclaimed(ctx, xmsg); err != nil {
+				q.logger.Error("failed to process reclaimed message",
+					zap.Error(err), zap.String("message_id", xmsg.ID))
+				continue
+			}
+			reclaimed++
+		}
+		if q.metrics != nil && len(entries) > 0 {
+			q.metrics.IncMessagesReclaimed(q.consumer, len(entries))
+		}
+
+		cursor = next
+		if cursor == "0-0" {
+			break
+		}
+	}
+	return reclaimed, nil
+}
+
+// handleReclaimed dead-letters xmsg if it's exhausted maxDeliveries,
+// otherwise re-queues it with Task.Attempt incremented by acking and
+// deleting the original PEL entry and XADDing a fresh one. The fresh entry
+// is left for ordinary XReadGroup delivery rather than handed directly to
+// this consumer, so a reclaimed task is delivered exactly once instead of
+// both through this path and whichever worker's Fetch reads the new entry.
+func (q *StreamQueue) handleReclaimed(ctx context.Context, xmsg redislib.XMessage) error {
+	task, err := taskcodec.Decode(xmsg.Values)
+	if err != nil {
+		return fmt.Errorf("decode reclaimed task: %w", err)
+	}
+
+	if q.maxDeliveries > 0 && task.Attempt+1 >= q.maxDeliveries {
+		msg := &ports.QueueMessage{ID: xmsg.ID, Task: task, Raw: toRawMap(xmsg.Values)}
+		if err := q.deadLetter(ctx, msg, errors.New("max deliveries reached while reclaiming idle message"), "redis_claim_max_deliveries"); err != nil {
+			return fmt.Errorf("dead letter reclaimed message: %w", err)
+		}
+		if err := q.client.XAck(ctx, q.stream, q.group, xmsg.ID).Err(); err != nil {
+			return fmt.Errorf("ack poison reclaimed message: %w", err)
+		}
+		return q.client.XDel(ctx, q.stream, xmsg.ID).Err()
+	}
+
+	nextTask := task
+	nextTask.Attempt++
+	values, err := taskcodec.Encode(nextTask)
+	if err != nil {
+		return fmt.Errorf("encode reclaimed task: %w", err)
+	}
+
+	if _, err := q.client.XAdd(ctx, &redislib.XAddArgs{Stream: q.stream, Values: values}).Result(); err != nil {
+		return fmt.Errorf("requeue reclaimed message: %w", err)
+	}
+	if err := q.client.XAck(ctx, q.stream, q.group, xmsg.ID).Err(); err != nil {
+		q.logger.Warn("failed to ack original reclaimed message", zap.Error(err), zap.String("message_id", xmsg.ID))
+	}
+	if err := q.client.XDel(ctx, q.stream, xmsg.ID).Err(); err != nil {
+		q.logger.Warn("failed to delete original reclaimed message", zap.Error(err), zap.String("message_id", xmsg.ID))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	redislib "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// retrySchedulerTick is how often RunRetryScheduler attempts leader
+// election and, if elected, promotes ready delayed retries.
+const retrySchedulerTick = 200 * time.Millisecond
+
+// retryPromotePageSize bounds how many delayed retries are promoted per
+// tick, so one very backed-up sweep doesn't block the scheduler loop.
+const retryPromotePageSize = 100
+
+// renewLeaderScript extends leaderKey's TTL only if this instance still
+// holds it, so a leader that lost the key to another instance (e.g. after
+// a GC pause longer than the lock TTL) doesn't clobber the new holder.
+const renewLeaderScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// promoteReadyScript atomically moves one delayed retry back onto the
+// stream: it only XADDs if the ZREM actually removed the member, so two
+// schedulers racing on the same entry (lock handoff, clock skew) can't
+// double-promote it.
+const promoteReadyScript = `
+local removed = redis.call("ZREM", KEYS[1], ARGV[1])
+if removed == 0 then
+  return 0
+end
+local fields = cjson.decode(ARGV[1])
+local args = {}
+for k, v in pairs(fields) do
+  table.insert(args, k)
+  table.insert(args, v)
+end
+redis.call("XADD", KEYS[2], "*", unpack(args))
+return 1`
+
+// RunRetryScheduler periodically promotes delayed retries (see
+// scheduleRetry) back onto the stream once their backoff has elapsed. Since
+// every worker instance runs one of these, it leader-elects via SET NX PX
+// on leaderKey so only one instance promotes at a time; a crashed leader's
+// lock simply expires and another instance takes over on its next tick.
+func (q *StreamQueue) RunRetryScheduler(ctx context.Context) {
+	ticker := time.NewTicker(retrySchedulerTick)
+	defer ticker.Stop()
+
+	leaderTTL := retrySchedulerTick * 5
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !q.acquireRetryLeader(ctx, leaderTTL) {
+				continue
+			}
+			if _, err := q.PromoteDelayed(ctx); err != nil {
+				q.logger.Error("delayed retry promotion failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// acquireRetryLeader reports whether this consumer holds (or just took) the
+// retry scheduler lock for ttl.
+func (q *StreamQueue) acquireRetryLeader(ctx context.Context, ttl time.Duration) bool {
+	acquired, err := q.client.SetNX(ctx, q.leaderKey, q.consumer, ttl).Result()
+	if err != nil {
+		q.logger.Error("retry scheduler leader election failed", zap.Error(err))
+		return false
+	}
+	if acquired {
+		return true
+	}
+
+	renewed, err := q.client.Eval(ctx, renewLeaderScript, []string{q.leaderKey}, q.consumer, ttl.Milliseconds()).Result()
+	if err != nil {
+		q.logger.Error("retry scheduler leader renewal failed", zap.Error(err))
+		return false
+	}
+	n, _ := renewed.(int64)
+	return n == 1
+}
+
+// PromoteDelayed moves up to retryPromotePageSize delayedKey entries whose
+// score (a Unix millisecond timestamp) has passed back onto the stream;
+// any further due entries wait for the next call. It also reports the
+// current retry_delayed_depth gauge. It's exported so a leader-elected
+// instance's scheduler loop and tests can both drive it.
+func (q *StreamQueue) PromoteDelayed(ctx context.Context) (int, error) {
+	nowMs := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	members, err := q.client.ZRangeByScore(ctx, q.delayedKey, &redislib.ZRangeBy{
+		Min:   "-inf",
+		Max:   nowMs,
+		Count: retryPromotePageSize,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	promoted := 0
+	for _, member := range members {
+		res, err := q.client.Eval(ctx, promoteReadyScript, []string{q.delayedKey, q.stream}, member).Result()
+		if err != nil {
+			q.logger.Error("failed to promote delayed retry", zap.Error(err))
+			continue
+		}
+		if n, ok := res.(int64); ok && n == 1 {
+			promoted++
+		}
+	}
+
+	if q.metrics != nil {
+		if depth, err := q.client.ZCard(ctx, q.delayedKey).Result(); err == nil {
+			q.metrics.SetRetryDelayedDepth(depth)
+		}
+	}
+	return promoted, nil
+}
+
+// retryDelay computes base * 2^attempt capped at max (if set) plus up to
+// jitter of random slack, so repeated failures of the same task back off
+// instead of retrying in a hot loop.
+func retryDelay(attempt int, base, max, jitter time.Duration) time.Duration {
+	shift := attempt
+	if shift > 32 {
+		shift = 32
+	}
+	delay := base * time.Duration(int64(1)<<uint(shift))
+	if max > 0 && delay > max {
+		delay = max
+	}
+	if jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	return delay
+}
@@ -2,15 +2,15 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"time"
 
 	redislib "github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
-	"github.com/alejandro/video-worker/internal/core/domain"
+	"github.com/alejandro/video-worker/internal/adapters/taskcodec"
 	"github.com/alejandro/video-worker/internal/core/ports"
 )
 
@@ -21,10 +21,38 @@ type StreamQueue struct {
 	consumer      string
 	blockTimeout  time.Duration
 	maxDeliveries int
-	logger        *zap.Logger
-	metrics       ports.Metrics
+	minIdleTime   time.Duration
+	// dlqStream receives tasks Fail or handleReclaimed gives up on after
+	// maxDeliveries, so they can be inspected and replayed through a
+	// DeadLetterStore instead of being dropped.
+	dlqStream string
+
+	// delayedKey is the sorted set Fail schedules retries into instead of
+	// re-XADDing them immediately, scored by the Unix millisecond timestamp
+	// the retry becomes eligible; leaderKey is the SET NX PX lock
+	// RunRetryScheduler uses so only one worker instance promotes ready
+	// retries at a time.
+	delayedKey string
+	leaderKey  string
+	// retryBaseDelay/retryMaxDelay/retryJitter configure the exponential
+	// backoff applied between retryDelay(attempt) calls.
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	retryJitter    time.Duration
+
+	logger  *zap.Logger
+	metrics ports.Metrics
 }
 
+// NewStreamQueue builds a StreamQueue against an existing consumer group
+// (created if missing). minIdleTime is how long a pending message must sit
+// unacknowledged before RunReclaimer's XAUTOCLAIM sweep claims it from a
+// crashed consumer; zero defaults to 5 minutes. dlqStream is where Fail and
+// the reclaimer send tasks that exhaust maxDeliveries; empty defaults to
+// stream + ":dlq". retryBaseDelay/retryMaxDelay/retryJitter configure the
+// backoff Fail applies before a failed task becomes eligible for
+// redelivery (RunRetryScheduler then promotes it once that elapses);
+// retryBaseDelay <= 0 defaults to one second.
 func NewStreamQueue(
 	ctx context.Context,
 	client *redislib.Client,
@@ -33,12 +61,26 @@ func NewStreamQueue(
 	consumer string,
 	blockTimeout time.Duration,
 	maxDeliveries int,
+	minIdleTime time.Duration,
+	dlqStream string,
+	retryBaseDelay time.Duration,
+	retryMaxDelay time.Duration,
+	retryJitter time.Duration,
 	logger *zap.Logger,
 	metrics ports.Metrics,
 ) (*StreamQueue, error) {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if minIdleTime <= 0 {
+		minIdleTime = 5 * time.Minute
+	}
+	if dlqStream == "" {
+		dlqStream = stream + ":dlq"
+	}
+	if retryBaseDelay <= 0 {
+		retryBaseDelay = time.Second
+	}
 	if err := client.XGroupCreateMkStream(ctx, stream, group, "0").Err(); err != nil {
 		if err.Error() != "BUSYGROUP Consumer Group name already exists" {
 			return nil, fmt.Errorf("create consumer group: %w", err)
@@ -46,14 +88,21 @@ func NewStreamQueue(
 	}
 
 	return &StreamQueue{
-		client:        client,
-		stream:        stream,
-		group:         group,
-		consumer:      consumer,
-		blockTimeout:  blockTimeout,
-		maxDeliveries: maxDeliveries,
-		logger:        logger,
-		metrics:       metrics,
+		client:         client,
+		stream:         stream,
+		group:          group,
+		consumer:       consumer,
+		blockTimeout:   blockTimeout,
+		maxDeliveries:  maxDeliveries,
+		minIdleTime:    minIdleTime,
+		dlqStream:      dlqStream,
+		delayedKey:     stream + ":delayed",
+		leaderKey:      stream + ":delayed:leader",
+		retryBaseDelay: retryBaseDelay,
+		retryMaxDelay:  retryMaxDelay,
+		retryJitter:    retryJitter,
+		logger:         logger,
+		metrics:        metrics,
 	}, nil
 }
 
@@ -64,7 +113,7 @@ func (q *StreamQueue) Fetch(ctx context.Context) (*ports.QueueMessage, error) {
 		if err != nil {
 			q.logger.Warn("failed to get stream size", zap.Error(err))
 		} else {
-			q.metrics.SetStreamSize(q.consumer, streamSize)
+			q.metrics.SetQueueDepth(q.consumer, "redis", streamSize)
 			q.logger.Debug("stream size", zap.Int64("size", streamSize), zap.String("worker", q.consumer))
 		}
 	}
@@ -90,7 +139,10 @@ func (q *StreamQueue) Fetch(ctx context.Context) (*ports.QueueMessage, error) {
 	}
 
 	xmsg := streams[0].Messages[0]
-	task := hydrateTask(xmsg.Values)
+	task, err := taskcodec.Decode(xmsg.Values)
+	if err != nil {
+		return nil, fmt.Errorf("decode task: %w", err)
+	}
 
 	return &ports.QueueMessage{
 		ID:   xmsg.ID,
@@ -119,50 +171,127 @@ func (q *StreamQueue) Fail(ctx context.Context, msg *ports.QueueMessage, reason
 	}
 
 	if q.maxDeliveries > 0 && msg.Task.Attempt+1 >= q.maxDeliveries {
-		q.logger.Warn("discarding message after max deliveries", zap.String("task_id", msg.Task.ID))
-		return nil
+		return q.deadLetter(ctx, msg, reason, "redis_max_deliveries")
 	}
 
-	values := map[string]any{
-		"task_id":     msg.Task.ID,
-		"video_id":    msg.Task.VideoID,
-		"source_path": msg.Task.SourcePath,
-		"attempt":     msg.Task.Attempt + 1,
+	return q.scheduleRetry(ctx, msg, reason)
+}
+
+// scheduleRetry ZADDs msg into q.delayedKey scored by the Unix millisecond
+// timestamp it becomes eligible for redelivery, instead of re-XADDing it
+// onto the stream immediately the way Fail used to. RunRetryScheduler moves
+// it back onto the stream once that score has passed, so a failing task
+// backs off instead of hot-looping through ffmpeg/storage.
+func (q *StreamQueue) scheduleRetry(ctx context.Context, msg *ports.QueueMessage, reason error) error {
+	nextTask := msg.Task
+	nextTask.Attempt++
+
+	fields, err := taskcodec.Encode(nextTask)
+	if err != nil {
+		return fmt.Errorf("encode delayed retry payload: %w", err)
 	}
 	if reason != nil {
-		values["error"] = reason.Error()
+		fields["error"] = reason.Error()
 	}
-	for k, v := range msg.Raw {
-		if _, exists := values[k]; !exists {
-			values[k] = v
-		}
+
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("marshal delayed retry payload: %w", err)
 	}
 
-	return q.client.XAdd(ctx, &redislib.XAddArgs{Stream: q.stream, Values: values}).Err()
+	delay := retryDelay(msg.Task.Attempt, q.retryBaseDelay, q.retryMaxDelay, q.retryJitter)
+	score := float64(time.Now().Add(delay).UnixMilli())
+	if err := q.client.ZAdd(ctx, q.delayedKey, redislib.Z{Score: score, Member: string(payload)}).Err(); err != nil {
+		return fmt.Errorf("zadd delayed retry: %w", err)
+	}
+
+	if q.metrics != nil {
+		if depth, err := q.client.ZCard(ctx, q.delayedKey).Result(); err == nil {
+			q.metrics.SetRetryDelayedDepth(depth)
+		}
+	}
+	return nil
 }
 
-func hydrateTask(values map[string]any) domain.Task {
-	task := domain.Task{Metadata: make(map[string]string)}
-
-	for key, value := range values {
-		strVal := fmt.Sprint(value)
-		switch key {
-		case "task_id":
-			task.ID = strVal
-		case "video_id":
-			task.VideoID = strVal
-		case "source_path":
-			task.SourcePath = strVal
-		case "attempt":
-			if attempt, err := strconv.Atoi(strVal); err == nil {
-				task.Attempt = attempt
-			}
-		default:
-			task.Metadata[key] = strVal
+// Requeue re-schedules msg for redelivery after retryBaseDelay through the
+// same delayedKey ZSET scheduleRetry uses, but leaves Task.Attempt untouched
+// and never checks maxDeliveries. It's for callers where a refetch isn't a
+// processing failure - currently TaskLocker contention in
+// ProcessVideoUseCase.HandleNext, where another in-flight attempt already
+// holds the lock for the same video - so that redelivery doesn't eat into
+// the task's real retry budget.
+func (q *StreamQueue) Requeue(ctx context.Context, msg *ports.QueueMessage) error {
+	if msg == nil {
+		return errors.New("queue message is nil")
+	}
+
+	if err := q.client.XAck(ctx, q.stream, q.group, msg.ID).Err(); err != nil {
+		q.logger.Error("failed to ack requeued message", zap.Error(err), zap.String("message_id", msg.ID))
+	}
+
+	fields, err := taskcodec.Encode(msg.Task)
+	if err != nil {
+		return fmt.Errorf("encode requeue payload: %w", err)
+	}
+
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("marshal requeue payload: %w", err)
+	}
+
+	score := float64(time.Now().Add(q.retryBaseDelay).UnixMilli())
+	if err := q.client.ZAdd(ctx, q.delayedKey, redislib.Z{Score: score, Member: string(payload)}).Err(); err != nil {
+		return fmt.Errorf("zadd requeue: %w", err)
+	}
+
+	if q.metrics != nil {
+		if depth, err := q.client.ZCard(ctx, q.delayedKey).Result(); err == nil {
+			q.metrics.SetRetryDelayedDepth(depth)
 		}
 	}
+	return nil
+}
+
+// deadLetter moves msg to q.dlqStream once it has exhausted maxDeliveries,
+// preserving enough of the original message (including a JSON snapshot of
+// msg.Raw) for a DeadLetterStore to replay it later. metricReason labels
+// the IncTaskDeadLettered call so Fail and the reclaimer's own max-delivery
+// branch remain distinguishable in metrics.
+func (q *StreamQueue) deadLetter(ctx context.Context, msg *ports.QueueMessage, reason error, metricReason string) error {
+	rawJSON, err := json.Marshal(msg.Raw)
+	if err != nil {
+		return fmt.Errorf("marshal raw message for dead letter: %w", err)
+	}
 
-	return task
+	lastError := ""
+	if reason != nil {
+		lastError = reason.Error()
+	}
+
+	values := map[string]any{
+		"task_id":     msg.Task.ID,
+		"video_id":    msg.Task.VideoID,
+		"source_path": msg.Task.SourcePath,
+		"original_id": msg.ID,
+		"last_error":  lastError,
+		"failed_at":   time.Now().UTC().Format(time.RFC3339),
+		"attempts":    msg.Task.Attempt + 1,
+		"raw_json":    string(rawJSON),
+	}
+
+	if _, err := q.client.XAdd(ctx, &redislib.XAddArgs{Stream: q.dlqStream, Values: values}).Result(); err != nil {
+		return fmt.Errorf("xadd dead letter: %w", err)
+	}
+
+	q.logger.Warn("moved message to dead-letter stream after max deliveries",
+		zap.String("task_id", msg.Task.ID), zap.String("message_id", msg.ID))
+	if q.metrics != nil {
+		q.metrics.IncTaskDeadLettered(metricReason)
+		if depth, err := q.client.XLen(ctx, q.dlqStream).Result(); err == nil {
+			q.metrics.SetDLQDepth(depth)
+		}
+	}
+	return nil
 }
 
 func toRawMap(values map[string]any) map[string]any {
@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redislib "github.com/redis/go-redis/v9"
+
+	"github.com/alejandro/video-worker/internal/core/ports"
+)
+
+// lockKeyPrefix namespaces TaskLocker keys under video_worker:lock:<video_id>.
+const lockKeyPrefix = "video_worker:lock:"
+
+// refreshLockScript extends key's TTL only if it still holds owner's token,
+// so a worker that lost the lock to expiry (and another worker already
+// reclaimed it) can't blindly re-extend someone else's lock.
+const refreshLockScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// releaseLockScript deletes key only if it still holds owner's token, the
+// same compare-and-delete guard refreshLockScript applies to renewal.
+const releaseLockScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+// TaskLocker is the redis-backed ports.TaskLocker, built on SET NX PX for
+// acquisition and Lua compare-and-delete/compare-and-expire for safe
+// refresh and release.
+type TaskLocker struct {
+	client *redislib.Client
+}
+
+func NewTaskLocker(client *redislib.Client) *TaskLocker {
+	return &TaskLocker{client: client}
+}
+
+func lockKey(videoID string) string {
+	return lockKeyPrefix + videoID
+}
+
+func (l *TaskLocker) Acquire(ctx context.Context, videoID, token string, ttl time.Duration) error {
+	acquired, err := l.client.SetNX(ctx, lockKey(videoID), token, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("acquire video lock: %w", err)
+	}
+	if !acquired {
+		return ports.ErrLockHeld
+	}
+	return nil
+}
+
+func (l *TaskLocker) Refresh(ctx context.Context, videoID, token string, ttl time.Duration) error {
+	res, err := l.client.Eval(ctx, refreshLockScript, []string{lockKey(videoID)}, token, ttl.Milliseconds()).Result()
+	if err != nil {
+		return fmt.Errorf("refresh video lock: %w", err)
+	}
+	if n, _ := res.(int64); n != 1 {
+		return ports.ErrLockHeld
+	}
+	return nil
+}
+
+func (l *TaskLocker) Release(ctx context.Context, videoID, token string) error {
+	if _, err := l.client.Eval(ctx, releaseLockScript, []string{lockKey(videoID)}, token).Result(); err != nil {
+		return fmt.Errorf("release video lock: %w", err)
+	}
+	return nil
+}
@@ -1,22 +1,49 @@
 package s3
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"go.uber.org/zap"
+
+	"github.com/alejandro/video-worker/internal/adapters/storage/spool"
+	"github.com/alejandro/video-worker/internal/core/ports"
 )
 
+// s3MinPartSize is the smallest part size S3 accepts for any part other
+// than the last one of a multipart upload.
+const s3MinPartSize = 5 * 1024 * 1024
+
+// s3PartUploadAttempts is how many times a single part is retried before
+// the whole multipart upload is aborted.
+const s3PartUploadAttempts = 3
+
 type S3Storage struct {
-	client *s3.Client
-	bucket string
-	prefix string
-	logger *zap.Logger
+	client        *s3.Client
+	presignClient *s3.PresignClient
+	bucket        string
+	prefix        string
+	logger        *zap.Logger
+	metrics       ports.Metrics
+
+	partSize          int64
+	uploadConcurrency int
+
+	tempDir        string
+	spoolThreshold int64
 }
 
 // NewS3Storage creates a new S3 storage adapter
@@ -26,10 +53,20 @@ type S3Storage struct {
 // accessKey: AWS access key ID (optional, will use default credentials chain if empty)
 // secretKey: AWS secret access key (optional, will use default credentials chain if empty)
 // endpoint: Optional custom endpoint for S3-compatible services (e.g., MinIO)
-func NewS3Storage(region, bucket, prefix, accessKey, secretKey, endpoint string, logger *zap.Logger) (*S3Storage, error) {
+// partSize: size in bytes of each multipart upload part (floored to 5 MiB)
+// uploadConcurrency: number of parts uploaded in parallel per Upload call
+// tempDir/spoolThreshold: where and above what size Download spools to disk
+// instead of buffering in memory (see internal/adapters/storage/spool)
+func NewS3Storage(region, bucket, prefix, accessKey, secretKey, endpoint string, partSize int64, uploadConcurrency int, tempDir string, spoolThreshold int64, logger *zap.Logger, metrics ports.Metrics) (*S3Storage, error) {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if partSize < s3MinPartSize {
+		partSize = s3MinPartSize
+	}
+	if uploadConcurrency < 1 {
+		uploadConcurrency = 1
+	}
 
 	var cfg aws.Config
 	var err error
@@ -67,18 +104,27 @@ func NewS3Storage(region, bucket, prefix, accessKey, secretKey, endpoint string,
 	}
 
 	client := s3.NewFromConfig(cfg, clientOptions...)
+	presignClient := s3.NewPresignClient(client)
 
 	logger.Info("S3 storage initialized",
 		zap.String("region", region),
 		zap.String("bucket", bucket),
 		zap.String("prefix", prefix),
-		zap.String("endpoint", endpoint))
+		zap.String("endpoint", endpoint),
+		zap.Int64("part_size_bytes", partSize),
+		zap.Int("upload_concurrency", uploadConcurrency))
 
 	return &S3Storage{
-		client: client,
-		bucket: bucket,
-		prefix: prefix,
-		logger: logger,
+		client:            client,
+		presignClient:     presignClient,
+		bucket:            bucket,
+		prefix:            prefix,
+		logger:            logger,
+		metrics:           metrics,
+		partSize:          partSize,
+		uploadConcurrency: uploadConcurrency,
+		tempDir:           tempDir,
+		spoolThreshold:    spoolThreshold,
 	}, nil
 }
 
@@ -88,12 +134,39 @@ func (s *S3Storage) Download(ctx context.Context, remotePath string) (io.ReadClo
 		zap.String("bucket", s.bucket),
 		zap.String("key", key))
 
-	input := &s3.GetObjectInput{
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
+	})
+	if err != nil {
+		s.logger.Error("failed to head S3 object",
+			zap.String("bucket", s.bucket),
+			zap.String("key", key),
+			zap.Error(err))
+		return nil, err
+	}
+
+	size := aws.ToInt64(head.ContentLength)
+	if size > s.partSize*2 {
+		reader, err := s.downloadRanged(ctx, key, size)
+		if err != nil {
+			s.logger.Error("failed to range-download from S3",
+				zap.String("bucket", s.bucket),
+				zap.String("key", key),
+				zap.Error(err))
+			return nil, err
+		}
+		s.logger.Info("successfully downloaded file from S3 via ranged parts",
+			zap.String("bucket", s.bucket),
+			zap.String("key", key),
+			zap.Int64("content_length", size))
+		return reader, nil
 	}
 
-	result, err := s.client.GetObject(ctx, input)
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
 		s.logger.Error("failed to download from S3",
 			zap.String("bucket", s.bucket),
@@ -101,41 +174,328 @@ func (s *S3Storage) Download(ctx context.Context, remotePath string) (io.ReadClo
 			zap.Error(err))
 		return nil, err
 	}
+	defer func() {
+		_ = result.Body.Close()
+	}()
+
+	reader, err := spool.SpoolThreshold(s.tempDir, "s3-download-*", s.spoolThreshold, result.Body)
+	if err != nil {
+		s.logger.Error("failed to read object body from S3",
+			zap.String("bucket", s.bucket),
+			zap.String("key", key),
+			zap.Error(err))
+		return nil, err
+	}
 
 	s.logger.Info("successfully downloaded file from S3",
 		zap.String("bucket", s.bucket),
 		zap.String("key", key),
-		zap.Int64("content_length", aws.ToInt64(result.ContentLength)))
+		zap.Int64("content_length", size))
 
-	return result.Body, nil
+	return reader, nil
 }
 
+// downloadRanged fetches a large object as concurrent ranged GETs, each
+// part written to its offset in a temp file by a bounded pool of
+// uploadConcurrency goroutines (mirroring Upload's part-upload pool). The
+// returned ReadCloser is the assembled temp file, seeked back to the start.
+func (s *S3Storage) downloadRanged(ctx context.Context, key string, size int64) (io.ReadCloser, error) {
+	tmp, err := os.CreateTemp(s.tempDir, "s3-download-*")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	type part struct {
+		offset int64
+		length int64
+	}
+	var parts []part
+	for offset := int64(0); offset < size; offset += s.partSize {
+		length := s.partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		parts = append(parts, part{offset: offset, length: length})
+	}
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, s.uploadConcurrency)
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	for _, pt := range parts {
+		mu.Lock()
+		aborting := firstErr != nil
+		mu.Unlock()
+		if aborting {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(pt part) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			body, err := s.downloadRangeWithRetry(ctx, key, pt.offset, pt.length)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			if _, err := tmp.WriteAt(body, pt.offset); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(pt)
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	cleanup = false
+	return &spool.File{File: tmp}, nil
+}
+
+// downloadRangeWithRetry fetches one byte range, retrying transient
+// failures up to s3PartUploadAttempts times before giving up.
+func (s *S3Storage) downloadRangeWithRetry(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	byteRange := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+
+	var lastErr error
+	for attempt := 1; attempt <= s3PartUploadAttempts; attempt++ {
+		out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+			Range:  aws.String(byteRange),
+		})
+		if err == nil {
+			body, readErr := io.ReadAll(out.Body)
+			_ = out.Body.Close()
+			if readErr == nil {
+				s.metrics.IncS3PartDownloaded()
+				return body, nil
+			}
+			err = readErr
+		}
+		lastErr = err
+		if attempt < s3PartUploadAttempts {
+			s.metrics.IncS3PartRetry()
+			s.logger.Warn("retrying S3 ranged part download",
+				zap.String("key", key), zap.String("range", byteRange), zap.Int("attempt", attempt), zap.Error(err))
+		}
+	}
+	return nil, lastErr
+}
+
+// Upload streams data to S3 via the multipart API: parts are read from data
+// in fixed-size chunks and uploaded by a bounded pool of uploadConcurrency
+// goroutines, each buffering at most one part at a time. On any part or
+// completion failure the in-progress upload is aborted.
 func (s *S3Storage) Upload(ctx context.Context, remotePath string, data io.Reader) error {
 	key := s.prefix + "/" + remotePath
-	s.logger.Info("uploading to S3",
+	s.logger.Info("uploading to S3 via multipart upload",
 		zap.String("bucket", s.bucket),
 		zap.String("key", key),
 		zap.String("remote_path", remotePath),
 		zap.String("prefix", s.prefix))
 
-	input := &s3.PutObjectInput{
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
-		Body:   data,
+	})
+	if err != nil {
+		s.logger.Error("failed to create multipart upload", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	uploadID := created.UploadId
+
+	type uploadedPart struct {
+		number int32
+		etag   *string
+	}
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, s.uploadConcurrency)
+		mu       sync.Mutex
+		parts    []uploadedPart
+		firstErr error
+	)
+
+	partNumber := int32(0)
+	buf := make([]byte, s.partSize)
+readLoop:
+	for {
+		n, readErr := io.ReadFull(data, buf)
+		if n > 0 {
+			mu.Lock()
+			aborting := firstErr != nil
+			mu.Unlock()
+			if aborting {
+				break readLoop
+			}
+
+			partNumber++
+			num := partNumber
+			chunk := make([]byte, n)
+			copy(chunk, buf[:n])
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				etag, err := s.uploadPartWithRetry(ctx, key, uploadID, num, chunk)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					return
+				}
+				parts = append(parts, uploadedPart{number: num, etag: etag})
+			}()
+		}
+		switch {
+		case readErr == io.EOF, readErr == io.ErrUnexpectedEOF:
+			break readLoop
+		case readErr != nil:
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = readErr
+			}
+			mu.Unlock()
+			break readLoop
+		}
 	}
+	wg.Wait()
 
-	_, err := s.client.PutObject(ctx, input)
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return s.abortUpload(key, uploadID, firstErr)
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].number < parts[j].number })
+	completedParts := make([]types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		completedParts = append(completedParts, types.CompletedPart{ETag: p.etag, PartNumber: aws.Int32(p.number)})
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
 	if err != nil {
-		s.logger.Error("failed to upload to S3",
-			zap.String("bucket", s.bucket),
-			zap.String("key", key),
-			zap.Error(err))
-		return err
+		return s.abortUpload(key, uploadID, err)
 	}
 
 	s.logger.Info("successfully uploaded file to S3",
 		zap.String("bucket", s.bucket),
-		zap.String("key", key))
+		zap.String("key", key),
+		zap.Int("parts", len(completedParts)))
 
 	return nil
 }
+
+// uploadPartWithRetry uploads a single part, retrying transient failures up
+// to s3PartUploadAttempts times before giving up.
+func (s *S3Storage) uploadPartWithRetry(ctx context.Context, key string, uploadID *string, number int32, body []byte) (*string, error) {
+	var lastErr error
+	for attempt := 1; attempt <= s3PartUploadAttempts; attempt++ {
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(key),
+			UploadId:   uploadID,
+			PartNumber: aws.Int32(number),
+			Body:       bytes.NewReader(body),
+		})
+		if err == nil {
+			s.metrics.IncS3PartUploaded()
+			return out.ETag, nil
+		}
+		lastErr = err
+		if attempt < s3PartUploadAttempts {
+			s.metrics.IncS3PartRetry()
+			s.logger.Warn("retrying S3 part upload",
+				zap.String("key", key), zap.Int32("part", number), zap.Int("attempt", attempt), zap.Error(err))
+		}
+	}
+	return nil, lastErr
+}
+
+// abortUpload cancels an in-progress multipart upload after cause, using a
+// fresh context so the abort still happens when ctx itself was the cause.
+func (s *S3Storage) abortUpload(key string, uploadID *string, cause error) error {
+	s.logger.Error("aborting S3 multipart upload", zap.String("key", key), zap.Error(cause))
+
+	if _, err := s.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	}); err != nil {
+		s.logger.Error("failed to abort S3 multipart upload", zap.String("key", key), zap.Error(err))
+	}
+	s.metrics.IncS3UploadAborted()
+
+	return cause
+}
+
+// Presign returns a time-limited GET or PUT URL for remotePath so a private
+// bucket can be read from, or written to, directly without a fronting proxy.
+func (s *S3Storage) Presign(ctx context.Context, remotePath string, op ports.PresignOp, ttl time.Duration) (string, error) {
+	key := s.prefix + "/" + remotePath
+
+	var (
+		request *v4.PresignedHTTPRequest
+		err     error
+	)
+	switch op {
+	case ports.PresignPut:
+		request, err = s.presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		}, s3.WithPresignExpires(ttl))
+	default:
+		request, err = s.presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		}, s3.WithPresignExpires(ttl))
+	}
+	if err != nil {
+		s.logger.Error("failed to presign S3 object URL",
+			zap.String("bucket", s.bucket), zap.String("key", key), zap.Error(err))
+		return "", err
+	}
+
+	return request.URL, nil
+}
@@ -21,24 +21,42 @@ import (
 type SQSQueue struct {
 	client        *sqs.Client
 	queueURL      string
+	dlqURL        string
 	maxDeliveries int
 	waitTime      int32 // Long polling wait time in seconds
+	retryPolicy   ports.RetryPolicy
 	logger        *zap.Logger
 	metrics       ports.Metrics
 }
 
+// NewSQSQueue creates an SQS-backed MessageQueue.
+//
+// dlqURL is optional: when set, DeadLetter publishes the exhausted task
+// there with failure metadata instead of just logging and dropping it.
+// retryPolicy is optional: when nil, a ports.ExponentialBackoff with
+// maxDeliveries attempts, a 1s base, a 5m cap and 1s of jitter is used.
 func NewSQSQueue(
 	ctx context.Context,
 	queueURL string,
+	dlqURL string,
 	region string,
 	maxDeliveries int,
 	waitTime int32,
+	retryPolicy ports.RetryPolicy,
 	logger *zap.Logger,
 	metrics ports.Metrics,
 ) (*SQSQueue, error) {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if retryPolicy == nil {
+		retryPolicy = ports.ExponentialBackoff{
+			Attempts: maxDeliveries,
+			Base:     time.Second,
+			Cap:      5 * time.Minute,
+			Jitter:   time.Second,
+		}
+	}
 
 	// Load AWS config - uses IAM role credentials from EC2 instance when running on AWS
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
@@ -51,8 +69,10 @@ func NewSQSQueue(
 	return &SQSQueue{
 		client:        client,
 		queueURL:      queueURL,
+		dlqURL:        dlqURL,
 		maxDeliveries: maxDeliveries,
 		waitTime:      waitTime,
+		retryPolicy:   retryPolicy,
 		logger:        logger,
 		metrics:       metrics,
 	}, nil
@@ -68,7 +88,7 @@ func (q *SQSQueue) Fetch(ctx context.Context) (*ports.QueueMessage, error) {
 		if err == nil && attrs.Attributes != nil {
 			if countStr, ok := attrs.Attributes[string(types.QueueAttributeNameApproximateNumberOfMessages)]; ok {
 				if count, err := strconv.ParseInt(countStr, 10, 64); err == nil {
-					q.metrics.SetStreamSize("sqs-worker", count)
+					q.metrics.SetQueueDepth("sqs-worker", "sqs", count)
 					q.logger.Debug("queue size", zap.Int64("size", count))
 				}
 			}
@@ -151,18 +171,22 @@ func (q *SQSQueue) Ack(ctx context.Context, msg *ports.QueueMessage) error {
 	return nil
 }
 
+// Fail no longer re-publishes via SendMessage+DeleteMessage, which would
+// reset SQS's own ApproximateReceiveCount and defeat its native redrive
+// policy. Instead, when the task still has attempts left, it schedules the
+// retry with ChangeMessageVisibility using q.retryPolicy's backoff and
+// leaves the original message (and its receive count) alone. Once attempts
+// are exhausted it hands off to DeadLetter and removes the message here,
+// since this is the layer that decided to give up on it.
 func (q *SQSQueue) Fail(ctx context.Context, msg *ports.QueueMessage, reason error) error {
 	if msg == nil {
 		return errors.New("queue message is nil")
 	}
 
-	// Check if we've exceeded max deliveries
-	if q.maxDeliveries > 0 && msg.Task.Attempt+1 >= q.maxDeliveries {
-		q.logger.Warn("discarding message after max deliveries",
-			zap.String("task_id", msg.Task.ID),
-			zap.Int("attempt", msg.Task.Attempt+1),
-		)
-		// Delete the message to remove it from the queue
+	if msg.Task.Attempt+1 >= q.retryPolicy.MaxAttempts() {
+		if err := q.DeadLetter(ctx, msg, reason); err != nil {
+			return err
+		}
 		_, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
 			QueueUrl:      aws.String(q.queueURL),
 			ReceiptHandle: aws.String(msg.ID),
@@ -170,52 +194,94 @@ func (q *SQSQueue) Fail(ctx context.Context, msg *ports.QueueMessage, reason err
 		return err
 	}
 
-	// Prepare message body for retry with incremented attempt
-	messageBody := map[string]interface{}{
+	delay := q.retryPolicy.NextDelay(msg.Task.Attempt)
+	visibilityTimeout := int32(delay.Seconds())
+	if visibilityTimeout < 0 {
+		visibilityTimeout = 0
+	}
+
+	_, err := q.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(q.queueURL),
+		ReceiptHandle:     aws.String(msg.ID),
+		VisibilityTimeout: visibilityTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("change message visibility: %w", err)
+	}
+
+	if reason != nil {
+		q.logger.Warn("scheduled task retry with backoff",
+			zap.String("task_id", msg.Task.ID),
+			zap.Int("attempt", msg.Task.Attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(reason),
+		)
+	}
+
+	return nil
+}
+
+// DeadLetter publishes msg to the configured DLQ (a no-op beyond logging if
+// none is configured) with the original body plus failure metadata: how
+// many attempts were made, the last error, and a first_seen timestamp.
+// first_seen holds the time of dead-lettering; the time the task was first
+// received is not recorded. The source message is left for the caller to
+// delete or leave in place.
+func (q *SQSQueue) DeadLetter(ctx context.Context, msg *ports.QueueMessage, reason error) error {
+	if msg == nil {
+		return errors.New("queue message is nil")
+	}
+
+	q.logger.Warn("dead-lettering task after exhausting retries",
+		zap.String("task_id", msg.Task.ID),
+		zap.Int("attempts", msg.Task.Attempt+1),
+		zap.Error(reason),
+	)
+
+	if q.metrics != nil {
+		q.metrics.IncTaskDeadLettered(deadLetterReason(reason))
+	}
+
+	if q.dlqURL == "" {
+		return nil
+	}
+
+	body := map[string]interface{}{
 		"task_id":     msg.Task.ID,
 		"video_id":    msg.Task.VideoID,
 		"source_path": msg.Task.SourcePath,
-		"attempt":     msg.Task.Attempt + 1,
+		"attempts":    msg.Task.Attempt + 1,
+		"first_seen":  time.Now().UTC().Format(time.RFC3339),
 	}
-
 	if reason != nil {
-		messageBody["error"] = reason.Error()
+		body["last_error"] = reason.Error()
 	}
-
-	// Preserve other metadata
 	for k, v := range msg.Raw {
-		if k != "task_id" && k != "video_id" && k != "source_path" && k != "attempt" {
-			messageBody[k] = v
+		if _, exists := body[k]; !exists {
+			body[k] = v
 		}
 	}
 
-	// Serialize message body
-	bodyBytes, err := json.Marshal(messageBody)
+	bodyBytes, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("marshal message body: %w", err)
+		return fmt.Errorf("marshal dead letter body: %w", err)
 	}
 
-	// Send message back to queue for retry
-	_, err = q.client.SendMessage(ctx, &sqs.SendMessageInput{
-		QueueUrl:    aws.String(q.queueURL),
+	if _, err := q.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.dlqURL),
 		MessageBody: aws.String(string(bodyBytes)),
-	})
-
-	if err != nil {
-		return fmt.Errorf("send retry message: %w", err)
+	}); err != nil {
+		return fmt.Errorf("send dead letter message: %w", err)
 	}
 
-	// Delete the original message
-	_, err = q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(q.queueURL),
-		ReceiptHandle: aws.String(msg.ID),
-	})
+	return nil
+}
 
-	if err != nil {
-		q.logger.Error("failed to delete failed message", zap.Error(err), zap.String("message_id", msg.ID))
+func deadLetterReason(reason error) string {
+	if reason == nil {
+		return "unknown"
 	}
-
-	return nil
+	return "processing_error"
 }
 
 func hydrateTask(values map[string]interface{}, attempt int) domain.Task {
@@ -0,0 +1,72 @@
+// Package spool helps storage adapters avoid buffering large downloads
+// entirely in memory by writing them to a temp file instead.
+package spool
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// File is a ReadCloser backed by a temp file; Close both closes the file
+// handle and removes the file. Name returns the underlying file's path so
+// callers that can operate on a path directly (e.g. ffmpeg) can skip
+// re-copying the data.
+type File struct {
+	*os.File
+}
+
+func (f *File) Close() error {
+	name := f.File.Name()
+	closeErr := f.File.Close()
+	if removeErr := os.Remove(name); removeErr != nil && !os.IsNotExist(removeErr) && closeErr == nil {
+		closeErr = removeErr
+	}
+	return closeErr
+}
+
+// Spool copies src in full into a new temp file under dir (pattern follows
+// os.CreateTemp's rules) and returns it seeked back to the start. Close on
+// the result removes the file.
+func Spool(dir, pattern string, src io.Reader) (*File, error) {
+	tmp, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return &File{File: tmp}, nil
+}
+
+// SpoolThreshold reads src without copying it to disk as long as it yields
+// no more than threshold bytes; once that much has been read without
+// reaching EOF, the bytes read so far plus the remainder of src are spooled
+// to a temp file via Spool. A non-positive threshold always spools.
+func SpoolThreshold(dir, pattern string, threshold int64, src io.Reader) (io.ReadCloser, error) {
+	if threshold <= 0 {
+		return Spool(dir, pattern, src)
+	}
+
+	buf := make([]byte, threshold+1)
+	n, err := io.ReadFull(src, buf)
+	switch {
+	case err == nil:
+		// More data remains beyond the threshold; spool the prefix we
+		// already consumed plus whatever is left of src.
+		return Spool(dir, pattern, io.MultiReader(bytes.NewReader(buf[:n]), src))
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+		return io.NopCloser(bytes.NewReader(buf[:n])), nil
+	default:
+		return nil, err
+	}
+}
@@ -0,0 +1,148 @@
+// Package taskcodec encodes and decodes domain.Task as the field(s) a
+// message-queue backend writes onto the wire, so every call site that
+// builds or reads a queue message (StreamQueue.Fetch/Fail, the reclaimer,
+// the dead-letter store's replay path) shares one versioned contract
+// instead of each hand-rolling its own flat-field mapping.
+package taskcodec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/alejandro/video-worker/internal/core/domain"
+)
+
+// SchemaV1 is the legacy flat-field encoding: every domain.Task field gets
+// its own wire field (task_id, video_id, source_path, attempt), with
+// anything else landing in Metadata as a string. Decode falls back to it
+// when a message carries no FieldSchema field at all, so messages written
+// before this package existed still decode correctly.
+const SchemaV1 = "v1"
+
+// SchemaV2 JSON-encodes the whole task into a single FieldPayload field
+// alongside FieldSchema naming the version, so adding a task field never
+// requires every consumer to learn a new wire field name.
+const SchemaV2 = "v2"
+
+// CurrentSchema is the schema Encode writes.
+const CurrentSchema = SchemaV2
+
+// FieldSchema and FieldPayload are the two wire fields a SchemaV2+ message
+// carries: FieldSchema names the version, FieldPayload holds its
+// JSON-encoded body.
+const (
+	FieldSchema  = "schema"
+	FieldPayload = "payload"
+)
+
+// taskV2 is the wire shape SchemaV2's payload field JSON-encodes. It's kept
+// distinct from domain.Task so a future schema can evolve the wire
+// representation without changing the in-memory type every use case
+// already depends on.
+type taskV2 struct {
+	ID         string            `json:"id"`
+	VideoID    string            `json:"video_id"`
+	SourcePath string            `json:"source_path"`
+	Attempt    int               `json:"attempt"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// migrations maps a schema version to the function that upgrades its
+// decoded payload to the current domain.Task. Registering a new entry here
+// is all a future schema bump needs to stay decodable by Decode.
+var migrations = map[string]func(payload []byte) (domain.Task, error){
+	SchemaV2: migrateV2,
+}
+
+// Encode returns the wire fields a producer should write for task, always
+// under CurrentSchema.
+func Encode(task domain.Task) (map[string]any, error) {
+	payload, err := json.Marshal(taskV2{
+		ID:         task.ID,
+		VideoID:    task.VideoID,
+		SourcePath: task.SourcePath,
+		Attempt:    task.Attempt,
+		Metadata:   task.Metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal task payload: %w", err)
+	}
+	return map[string]any{
+		FieldSchema:  CurrentSchema,
+		FieldPayload: string(payload),
+	}, nil
+}
+
+// Decode reconstructs a domain.Task from a message's wire fields. A
+// message with no FieldSchema field is assumed to be SchemaV1; otherwise
+// its registered migration for that schema unmarshals FieldPayload and
+// upgrades it to the current domain.Task. It returns an error for a
+// FieldSchema value with no registered migration.
+func Decode(values map[string]any) (domain.Task, error) {
+	schemaVal, ok := values[FieldSchema]
+	if !ok {
+		return decodeV1(values), nil
+	}
+
+	schema := fmt.Sprint(schemaVal)
+	if schema == SchemaV1 {
+		return decodeV1(values), nil
+	}
+
+	migrate, ok := migrations[schema]
+	if !ok {
+		return domain.Task{}, fmt.Errorf("taskcodec: unsupported schema %q", schema)
+	}
+
+	payloadVal, ok := values[FieldPayload]
+	if !ok {
+		return domain.Task{}, fmt.Errorf("taskcodec: schema %q message missing %s field", schema, FieldPayload)
+	}
+	return migrate([]byte(fmt.Sprint(payloadVal)))
+}
+
+// migrateV2 JSON-unmarshals a SchemaV2 payload. SchemaV2 is the current
+// schema, so no field translation is needed yet.
+func migrateV2(payload []byte) (domain.Task, error) {
+	var v2 taskV2
+	if err := json.Unmarshal(payload, &v2); err != nil {
+		return domain.Task{}, fmt.Errorf("unmarshal v2 task payload: %w", err)
+	}
+	metadata := v2.Metadata
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	return domain.Task{
+		ID:         v2.ID,
+		VideoID:    v2.VideoID,
+		SourcePath: v2.SourcePath,
+		Attempt:    v2.Attempt,
+		Metadata:   metadata,
+	}, nil
+}
+
+// decodeV1 flattens every wire field into a domain.Task: task_id/video_id/
+// source_path/attempt are typed fields, anything else lands in Metadata as
+// a string.
+func decodeV1(values map[string]any) domain.Task {
+	task := domain.Task{Metadata: make(map[string]string)}
+	for key, value := range values {
+		strVal := fmt.Sprint(value)
+		switch key {
+		case "task_id":
+			task.ID = strVal
+		case "video_id":
+			task.VideoID = strVal
+		case "source_path":
+			task.SourcePath = strVal
+		case "attempt":
+			if attempt, err := strconv.Atoi(strVal); err == nil {
+				task.Attempt = attempt
+			}
+		default:
+			task.Metadata[key] = strVal
+		}
+	}
+	return task
+}
@@ -0,0 +1,114 @@
+package taskcodec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alejandro/video-worker/internal/core/domain"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	task := domain.Task{
+		ID:         "task-1",
+		VideoID:    "video-1",
+		SourcePath: "/videos/source.mp4",
+		Attempt:    2,
+		Metadata:   map[string]string{"tenant": "anb"},
+	}
+
+	fields, err := Encode(task)
+	if err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if fields[FieldSchema] != CurrentSchema {
+		t.Fatalf("expected schema %q, got %v", CurrentSchema, fields[FieldSchema])
+	}
+
+	decoded, err := Decode(fields)
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, task) {
+		t.Fatalf("expected round-tripped task %+v, got %+v", task, decoded)
+	}
+}
+
+func TestDecodeV1LegacyFlatFields(t *testing.T) {
+	values := map[string]any{
+		"task_id":     "task-2",
+		"video_id":    "video-2",
+		"source_path": "source.mp4",
+		"attempt":     "1",
+		"tenant":      "anb",
+	}
+
+	decoded, err := Decode(values)
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+
+	want := domain.Task{
+		ID:         "task-2",
+		VideoID:    "video-2",
+		SourcePath: "source.mp4",
+		Attempt:    1,
+		Metadata:   map[string]string{"tenant": "anb"},
+	}
+	if decoded.ID != want.ID || decoded.VideoID != want.VideoID || decoded.SourcePath != want.SourcePath || decoded.Attempt != want.Attempt {
+		t.Fatalf("expected %+v, got %+v", want, decoded)
+	}
+	if decoded.Metadata["tenant"] != "anb" {
+		t.Fatalf("expected metadata to carry unrecognized field, got %+v", decoded.Metadata)
+	}
+}
+
+func TestDecodeExplicitV1Schema(t *testing.T) {
+	values := map[string]any{
+		"schema":      "v1",
+		"task_id":     "task-3",
+		"video_id":    "video-3",
+		"source_path": "source.mp4",
+		"attempt":     "0",
+	}
+
+	decoded, err := Decode(values)
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if decoded.ID != "task-3" || decoded.VideoID != "video-3" {
+		t.Fatalf("unexpected decode for explicit v1 schema: %+v", decoded)
+	}
+}
+
+func TestDecodeUnsupportedSchema(t *testing.T) {
+	_, err := Decode(map[string]any{"schema": "v99", "payload": "{}"})
+	if err == nil {
+		t.Fatalf("expected error for unsupported schema")
+	}
+}
+
+func TestDecodeMixedStream(t *testing.T) {
+	v1 := map[string]any{
+		"task_id":     "task-v1",
+		"video_id":    "video-v1",
+		"source_path": "v1.mp4",
+		"attempt":     "0",
+	}
+
+	v2Task := domain.Task{ID: "task-v2", VideoID: "video-v2", SourcePath: "v2.mp4", Attempt: 1, Metadata: map[string]string{}}
+	v2, err := Encode(v2Task)
+	if err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+
+	stream := []map[string]any{v1, v2, v1}
+	for i, values := range stream {
+		decoded, err := Decode(values)
+		if err != nil {
+			t.Fatalf("message %d: Decode returned error: %v", i, err)
+		}
+		if decoded.ID == "" {
+			t.Fatalf("message %d: expected a task id, got empty", i)
+		}
+	}
+}
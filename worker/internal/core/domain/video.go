@@ -22,14 +22,19 @@ type Video struct {
 	ProcessedAt      *time.Time
 	OriginalURL      string
 	ProcessedURL     *string
-	Votes            int
+	// ProcessedPlaybackURL is a presigned, time-limited URL for the
+	// processed video, set when Storage supports one. Falls back to
+	// ProcessedURL (the raw storage path) when nil.
+	ProcessedPlaybackURL *string
+	Votes                int
 }
 
-func (v *Video) MarkProcessed(processedAt time.Time, processedVideoID, processedURL string) {
+func (v *Video) MarkProcessed(processedAt time.Time, processedVideoID, processedURL, playbackURL string) {
 	v.Status = VideoStatusProcessed
 	v.ProcessedAt = &processedAt
 	v.ProcessedVideoID = optionalString(processedVideoID)
 	v.ProcessedURL = optionalString(processedURL)
+	v.ProcessedPlaybackURL = optionalString(playbackURL)
 }
 
 func (v *Video) ResetToUploaded() {
@@ -37,6 +42,7 @@ func (v *Video) ResetToUploaded() {
 	v.ProcessedAt = nil
 	v.ProcessedVideoID = nil
 	v.ProcessedURL = nil
+	v.ProcessedPlaybackURL = nil
 }
 
 func optionalString(value string) *string {
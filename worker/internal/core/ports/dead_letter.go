@@ -0,0 +1,53 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alejandro/video-worker/internal/core/domain"
+)
+
+// ErrDeadLetterNotFound is returned by DeadLetterStore.Get when id doesn't
+// name an entry currently in the dead-letter store (already requeued,
+// purged, or never existed).
+var ErrDeadLetterNotFound = errors.New("dead letter store: entry not found")
+
+// DeadLetterEntry is one task a MessageQueue gave up on after exhausting
+// its retry policy, as recorded in the dead-letter store.
+type DeadLetterEntry struct {
+	// ID identifies this entry within the dead-letter store.
+	ID string
+	// OriginalID is the id the message had on the main queue before it was
+	// dead-lettered.
+	OriginalID string
+	Task       domain.Task
+	// LastError is the failure reason recorded on the delivery that
+	// exhausted the retry policy.
+	LastError string
+	FailedAt  time.Time
+	// Attempts is how many times the task was delivered before being
+	// dead-lettered.
+	Attempts int
+	// Raw preserves the original message's fields, so Requeue can rebuild a
+	// message equivalent to the one that was dead-lettered.
+	Raw map[string]any
+}
+
+// DeadLetterStore lets operators inspect and replay tasks a MessageQueue
+// moved to a dead-letter queue after exhausting maxDeliveries, instead of
+// the failure being unrecoverable and uninspectable.
+type DeadLetterStore interface {
+	// List returns up to limit entries after cursor (an empty cursor starts
+	// from the oldest entry) and the cursor to pass in for the next page,
+	// or "" once there are no more entries.
+	List(ctx context.Context, cursor string, limit int) (entries []DeadLetterEntry, nextCursor string, err error)
+	// Get fetches one entry by ID, or ErrDeadLetterNotFound if it isn't
+	// there.
+	Get(ctx context.Context, id string) (*DeadLetterEntry, error)
+	// Requeue moves entry id back onto the main queue with its delivery
+	// attempts reset, then removes it from the dead-letter store.
+	Requeue(ctx context.Context, id string) error
+	// Purge permanently deletes the given entries without requeuing them.
+	Purge(ctx context.Context, ids ...string) error
+}
@@ -0,0 +1,35 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLeaseHeld is returned by TaskLedger.Claim when another worker already
+// holds a live lease on the task.
+var ErrLeaseHeld = errors.New("task ledger: lease held by another worker")
+
+// ErrAlreadyCompleted is returned by TaskLedger.Claim when the task already
+// ran to completion, so the caller should skip reprocessing it.
+var ErrAlreadyCompleted = errors.New("task ledger: task already completed")
+
+// TaskLedger makes task processing idempotent under at-least-once queue
+// delivery. A worker must Claim a task before running it and Complete it
+// afterward; a task redelivered after a crash or an SQSQueue.Fail requeue
+// then either finds its previous lease expired (Claim succeeds again) or
+// finds it already Complete (Claim reports ErrAlreadyCompleted) instead of
+// being processed, and potentially double-uploaded, twice.
+type TaskLedger interface {
+	// Claim takes ownership of taskID for workerID for leaseTTL. It returns
+	// ErrAlreadyCompleted if the task already finished, or ErrLeaseHeld if
+	// another worker holds a still-live lease.
+	Claim(ctx context.Context, taskID, videoID, workerID string, leaseTTL time.Duration) error
+	// Complete marks taskID done and records resultHash, so a later Claim
+	// for the same taskID short-circuits with ErrAlreadyCompleted.
+	Complete(ctx context.Context, taskID, resultHash string) error
+	// Release gives up a held lease without marking the task complete, so
+	// another worker can Claim it immediately instead of waiting out the
+	// lease.
+	Release(ctx context.Context, taskID, workerID string) error
+}
@@ -6,5 +6,56 @@ type Metrics interface {
 	IncQueueError(workerID string)
 	IncTaskProcessed(status string, workerID string)
 	ObserveProcessingDuration(status string, workerID string, d time.Duration)
-	SetStreamSize(workerID string, size int64)
+	// SetQueueDepth reports how many messages are waiting in the queue
+	// backend (e.g. "redis", "kafka", "sqs", "nats") for consumer, so the
+	// same gauge covers every ports.MessageQueue implementation instead of
+	// each backend needing its own.
+	SetQueueDepth(consumer, backend string, depth int64)
+	// SetFFmpegQueueDepth reports how many jobs are waiting in the shared
+	// ffmpeg.WorkerPool queue.
+	SetFFmpegQueueDepth(depth int64)
+	// SetFFmpegActiveWorkers reports how many ffmpeg/ffprobe subprocesses
+	// the shared worker pool currently has in flight.
+	SetFFmpegActiveWorkers(count int64)
+	// IncS3PartUploaded counts one successfully uploaded S3 multipart part.
+	IncS3PartUploaded()
+	// IncS3PartRetry counts one retried S3 multipart part transfer attempt,
+	// upload or download.
+	IncS3PartRetry()
+	// IncS3UploadAborted counts one S3 multipart upload that was aborted
+	// after a part or completion failure.
+	IncS3UploadAborted()
+	// IncS3PartDownloaded counts one successfully fetched S3 ranged-GET
+	// download part.
+	IncS3PartDownloaded()
+	// ObserveBytesTransferred adds n bytes moved during phase (e.g.
+	// "download", "transcode", "upload") by workerID.
+	ObserveBytesTransferred(phase, workerID string, n int64)
+	// SetTaskProgress reports taskID's percent-complete (0-100) for phase.
+	SetTaskProgress(taskID, phase string, pct float64)
+	// IncPresignErrors counts one failed attempt to presign a processed
+	// video's playback URL.
+	IncPresignErrors()
+	// IncTaskDeadLettered counts one task moved to a dead-letter queue
+	// after exhausting its retry policy, labeled by reason.
+	IncTaskDeadLettered(reason string)
+	// IncEncoderUsed counts one job encoded with the given video encoder
+	// (e.g. "libx264", "h264_vaapi"), so operators can see how many jobs
+	// used each backend.
+	IncEncoderUsed(encoder string)
+	// ObserveChunkDuration records how long one chunk of a chunked-pipeline
+	// encode (VideoProcessingOptions.ChunkSeconds) took to transcode.
+	ObserveChunkDuration(d time.Duration)
+	// IncMessagesReclaimed counts messages a queue's idle-consumer reaper
+	// (e.g. redis.StreamQueue.Reclaim) claimed back from a crashed
+	// consumer's pending entries, labeled by the claiming consumer.
+	IncMessagesReclaimed(consumer string, count int)
+	// SetDLQDepth reports how many entries currently sit in a queue
+	// backend's dead-letter store (e.g. redis.DeadLetterStore), so operators
+	// can alert on a growing backlog of poison messages.
+	SetDLQDepth(depth int64)
+	// SetRetryDelayedDepth reports how many failed tasks are currently
+	// waiting out their backoff in a queue backend's delayed-retry set
+	// (e.g. redis.StreamQueue's delayedKey) before redelivery.
+	SetRetryDelayedDepth(depth int64)
 }
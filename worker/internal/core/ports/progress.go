@@ -0,0 +1,11 @@
+package ports
+
+import "context"
+
+// ProgressSink receives percent-complete updates for one phase (download,
+// transcode, upload) of a task, e.g. to persist progress rows into Postgres
+// for a frontend to poll. Implementations should treat ReportProgress as
+// best-effort: a failure here must never fail the task itself.
+type ProgressSink interface {
+	ReportProgress(ctx context.Context, taskID, phase string, pct float64) error
+}
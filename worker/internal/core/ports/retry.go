@@ -0,0 +1,63 @@
+package ports
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides how long to wait before a failed task is retried and
+// how many deliveries it gets before it should be dead-lettered instead.
+// Shared by every queue adapter so backoff behaves the same regardless of
+// backend.
+type RetryPolicy interface {
+	// MaxAttempts is how many deliveries (including the first) a task gets
+	// before the caller should dead-letter it instead of retrying.
+	MaxAttempts() int
+	// NextDelay returns how long to wait before attempt (0-based, the
+	// attempt that just failed) is retried again.
+	NextDelay(attempt int) time.Duration
+}
+
+// ExponentialBackoff is the default RetryPolicy: delay doubles with each
+// attempt up to Cap, plus up to Jitter of random slack so a batch of
+// failures doesn't all retry on the same tick.
+type ExponentialBackoff struct {
+	Attempts int
+	Base     time.Duration
+	Cap      time.Duration
+	Jitter   time.Duration
+}
+
+func (b ExponentialBackoff) MaxAttempts() int {
+	if b.Attempts <= 0 {
+		return 1
+	}
+	return b.Attempts
+}
+
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	base := b.Base
+	if base <= 0 {
+		base = time.Second
+	}
+	cap := b.Cap
+	if cap <= 0 {
+		cap = 5 * time.Minute
+	}
+
+	shift := attempt
+	if shift < 0 {
+		shift = 0
+	}
+	if shift > 32 {
+		shift = 32
+	}
+
+	delay := time.Duration(math.Min(float64(base)*math.Pow(2, float64(shift)), float64(cap)))
+
+	if b.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(b.Jitter)))
+	}
+	return delay
+}
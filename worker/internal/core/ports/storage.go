@@ -2,10 +2,32 @@ package ports
 
 import (
 	"context"
+	"errors"
 	"io"
+	"time"
+)
+
+// ErrUnsupported is returned by a Storage implementation for a capability
+// it has no backing API for, e.g. Presign on a backend without a
+// signed-link mechanism. Callers should treat it as "not available", not
+// as a failure.
+var ErrUnsupported = errors.New("storage: operation not supported by this backend")
+
+// PresignOp identifies which operation a presigned URL authorizes.
+type PresignOp int
+
+const (
+	// PresignGet authorizes a time-limited direct download of remotePath.
+	PresignGet PresignOp = iota
+	// PresignPut authorizes a time-limited direct upload to remotePath.
+	PresignPut
 )
 
 type Storage interface {
 	Download(ctx context.Context, remotePath string) (io.ReadCloser, error)
 	Upload(ctx context.Context, remotePath string, data io.Reader) error
+	// Presign returns a time-limited URL authorizing op against remotePath,
+	// without going through a fronting proxy. It returns ErrUnsupported if
+	// the backend has no signed-link mechanism.
+	Presign(ctx context.Context, remotePath string, op PresignOp, ttl time.Duration) (string, error)
 }
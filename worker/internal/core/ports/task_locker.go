@@ -0,0 +1,30 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLockHeld is returned by TaskLocker.Acquire and Refresh when another
+// token currently holds the lock.
+var ErrLockHeld = errors.New("task locker: lock held by another worker")
+
+// TaskLocker is a short-lived, per-video mutual-exclusion lock. Unlike
+// TaskLedger (which tracks task_id completion durably in Postgres so a
+// later redelivery is skipped), TaskLocker only guards the window a worker
+// is actively processing: it's held for the transcode's duration and
+// released (or left to expire) as soon as it's done, so a message that
+// SQSQueue.Fail requeues or a reaper reclaims from a crashed consumer can't
+// run concurrently with an attempt already in flight for the same VideoID.
+type TaskLocker interface {
+	// Acquire takes the lock for videoID, owned by token, for ttl. It
+	// returns ErrLockHeld if another token currently holds a live lock.
+	Acquire(ctx context.Context, videoID, token string, ttl time.Duration) error
+	// Refresh extends a held lock's TTL. It returns ErrLockHeld if token no
+	// longer owns the lock (e.g. it expired and another token claimed it).
+	Refresh(ctx context.Context, videoID, token string, ttl time.Duration) error
+	// Release gives up the lock if token still owns it; releasing a lock
+	// that already expired, or that another token now owns, is a no-op.
+	Release(ctx context.Context, videoID, token string) error
+}
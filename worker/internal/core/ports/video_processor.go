@@ -28,6 +28,82 @@ type WatermarkOptions struct {
 	MarginY       int
 	StartDuration time.Duration
 	EndDuration   time.Duration
+
+	// ImagePath is an optional logo image (PNG with alpha, or an
+	// already-rasterized SVG) overlaid alongside or instead of Text, using
+	// the same Position/MarginX/MarginY placement. ffmpeg has no native SVG
+	// decoder, so a caller with an SVG logo must rasterize it to PNG first.
+	ImagePath string
+	// ImageScale sizes the overlay as a fraction of the output width, e.g.
+	// 0.15 for a logo 15% as wide as the frame, preserving aspect ratio.
+	// Zero defaults to 0.15.
+	ImageScale float64
+	// ImageOpacity is the overlay's alpha multiplier, 0-1. Zero or >1
+	// defaults to 1 (fully opaque).
+	ImageOpacity float64
+}
+
+// OutputFormat selects whether Process produces a single progressive file
+// or a multi-rendition adaptive bitrate package.
+type OutputFormat string
+
+const (
+	OutputFormatMP4  OutputFormat = "mp4"
+	OutputFormatDASH OutputFormat = "dash"
+	OutputFormatHLS  OutputFormat = "hls"
+)
+
+// Rendition describes one rung of an adaptive bitrate ladder.
+type Rendition struct {
+	Name             string
+	Width            int
+	Height           int
+	VideoBitrateKbps int
+	AudioBitrateKbps int
+}
+
+// EncodingMode selects how Process controls the output's quality/bitrate
+// tradeoff for the single-file (non-ABR) encode path.
+type EncodingMode string
+
+const (
+	// EncodingSingleCRF runs one constant-quality pass with no explicit
+	// bitrate target. This is the zero value and today's default behavior.
+	EncodingSingleCRF EncodingMode = ""
+	// EncodingTwoPassBitrate runs ffmpeg twice: a first pass that measures
+	// the source's complexity, then a second pass that hits TargetKbps as
+	// closely as the encoder can, capped at MaxKbps. An encoder without
+	// two-pass support (the hardware ones) gets a single pass at TargetKbps
+	// instead.
+	EncodingTwoPassBitrate EncodingMode = "two_pass_bitrate"
+	// EncodingCappedCRF runs one constant-quality pass like EncodingSingleCRF
+	// but adds a VBV ceiling at MaxBitrateKbps, trading quality in complex
+	// scenes to keep a hard bitrate cap.
+	EncodingCappedCRF EncodingMode = "capped_crf"
+)
+
+// EncodingStrategy selects Process's rate-control approach and the budget
+// that goes with it. Only the fields relevant to Mode are consulted; the
+// zero value is EncodingSingleCRF with no CRF override, i.e. today's
+// behavior. Only consulted for the single-file (non-ABR, non-chunked)
+// pipeline, the same scope as ChunkSeconds and Thumbnails.
+type EncodingStrategy struct {
+	Mode EncodingMode
+
+	// CRF is EncodingSingleCRF's optional override of the encoder's own
+	// default constant-quality factor, and EncodingCappedCRF's required
+	// quality target. Zero means "use the encoder's default" for
+	// EncodingSingleCRF, or 23 for EncodingCappedCRF.
+	CRF int
+	// MaxBitrateKbps caps EncodingCappedCRF's peak bitrate via a VBV buffer
+	// sized at twice this. Zero means no cap. Ignored by other modes.
+	MaxBitrateKbps int
+
+	// TargetKbps and MaxKbps drive EncodingTwoPassBitrate: the second pass
+	// targets TargetKbps, with MaxKbps as the VBV ceiling. MaxKbps at or
+	// below TargetKbps means no extra ceiling beyond TargetKbps itself.
+	TargetKbps int
+	MaxKbps    int
 }
 
 type VideoProcessingOptions struct {
@@ -37,6 +113,91 @@ type VideoProcessingOptions struct {
 	TargetFormat string
 	RemoveAudio  bool
 	Watermark    *WatermarkOptions
+
+	// Encoding selects the rate-control strategy for the single-file
+	// (non-ABR) encode path. The zero value is EncodingSingleCRF, i.e.
+	// today's behavior.
+	Encoding EncodingStrategy
+
+	// OutputFormat selects single-file mp4 (the default) or a DASH/HLS
+	// adaptive bitrate package. Renditions is only consulted for dash/hls.
+	OutputFormat OutputFormat
+	Renditions   []Rendition
+
+	// EncoderProfile overrides, for this request only, which video encoder
+	// Process uses (e.g. "h264_nvenc"). Empty uses the VideoProcessor's
+	// auto-detected or operator-forced default.
+	EncoderProfile string
+
+	// ChunkSeconds splits the source into roughly this many seconds per
+	// chunk, transcodes the chunks in parallel through a bounded worker
+	// pool, and concat-demuxes the results with the curtain segments
+	// instead of running a single ffmpeg pass. Chunk boundaries are
+	// snapped to the nearest keyframe so the join doesn't re-encode across
+	// a GOP. Zero (the default) keeps the single-pass pipeline. Only
+	// consulted when OutputFormat is "" or OutputFormatMP4.
+	ChunkSeconds float64
+
+	// Thumbnails, when set, makes Process emit an extra sprite-sheet +
+	// WebVTT preview artifact alongside the processed video. Nil skips
+	// thumbnail generation. Only consulted when OutputFormat is "" or
+	// OutputFormatMP4.
+	Thumbnails *ThumbnailOptions
+}
+
+// ThumbnailOptions configures a scrubber-preview sprite sheet: a grid of
+// JPEG tiles sampled at a fixed interval from the processed video, plus a
+// WebVTT file mapping playback time to each tile's position in the sheet.
+type ThumbnailOptions struct {
+	// IntervalSeconds is how far apart each tile's source frame is, e.g. 5
+	// for one thumbnail every 5 seconds. Zero defaults to 5.
+	IntervalSeconds float64
+	// Columns and Rows size the sprite sheet's tile grid. Zero defaults to
+	// 5x5, i.e. 25 thumbnails per sheet.
+	Columns int
+	Rows    int
+	// Width is each tile's width in pixels; height is derived from the
+	// processed video's aspect ratio. Zero defaults to 160.
+	Width int
+}
+
+// ProcessedSegment is one file of an adaptive bitrate package: an init
+// segment, a media chunk, or a variant playlist.
+type ProcessedSegment struct {
+	// Path is relative to the bundle's per-video prefix, e.g.
+	// "init-360.mp4" or "chunk-720-00001.m4s".
+	Path        string
+	ContentType string
+	Reader      io.ReadCloser
+}
+
+// ProcessedBundle is the DASH/HLS output of Process: a manifest plus the
+// segments it references.
+type ProcessedBundle struct {
+	// ManifestPath is relative to the bundle's per-video prefix, e.g.
+	// "manifest.mpd" or "master.m3u8".
+	ManifestPath   string
+	ManifestReader io.ReadCloser
+	Segments       []ProcessedSegment
+}
+
+func (b *ProcessedBundle) Close() error {
+	if b == nil {
+		return nil
+	}
+	var firstErr error
+	if b.ManifestReader != nil {
+		firstErr = b.ManifestReader.Close()
+	}
+	for _, seg := range b.Segments {
+		if seg.Reader == nil {
+			continue
+		}
+		if err := seg.Reader.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
 type ProcessedVideo struct {
@@ -44,15 +205,65 @@ type ProcessedVideo struct {
 	Format   string
 	Duration time.Duration
 	Metadata map[string]string
+
+	// Bundle is set instead of Reader when OutputFormat is dash/hls.
+	Bundle *ProcessedBundle
+
+	// Artifacts holds side-files uploaded alongside Reader, e.g. a
+	// thumbnail sprite sheet and its WebVTT cue file. Empty unless
+	// VideoProcessingOptions.Thumbnails was set.
+	Artifacts []ProcessedArtifact
+}
+
+// ProcessedArtifact is a side-file produced alongside the main processed
+// video, uploaded under the processed video's ID plus PathSuffix, e.g.
+// ".sprite.jpg" or ".vtt".
+type ProcessedArtifact struct {
+	PathSuffix  string
+	ContentType string
+	Reader      io.ReadCloser
 }
 
+// SpriteArtifactPlaceholder is the literal token a thumbnail preview's
+// WebVTT cues use in place of the sprite sheet's final uploaded filename.
+// The sprite's path isn't known until the use case assigns the processed
+// video its ID, so Process embeds this placeholder and the caller
+// replaces it before uploading the VTT artifact.
+const SpriteArtifactPlaceholder = "{sprite}"
+
 func (p *ProcessedVideo) Close() error {
-	if p == nil || p.Reader == nil {
+	if p == nil {
 		return nil
 	}
-	return p.Reader.Close()
+	var firstErr error
+	if p.Bundle != nil {
+		firstErr = p.Bundle.Close()
+	}
+	for _, artifact := range p.Artifacts {
+		if artifact.Reader == nil {
+			continue
+		}
+		if err := artifact.Reader.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if p.Reader == nil {
+		return firstErr
+	}
+	if err := p.Reader.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
 }
 
 type VideoProcessor interface {
 	Process(ctx context.Context, input io.Reader, opts VideoProcessingOptions) (*ProcessedVideo, error)
 }
+
+// PathAwareVideoProcessor is optionally implemented by a VideoProcessor that
+// can read its input directly from a local file, skipping the copy into a
+// fresh temp file that Process performs for an arbitrary io.Reader. Callers
+// holding a source already spooled to disk should prefer this when available.
+type PathAwareVideoProcessor interface {
+	ProcessPath(ctx context.Context, inputPath string, opts VideoProcessingOptions) (*ProcessedVideo, error)
+}
@@ -1,18 +1,33 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
+	"path"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/alejandro/video-worker/internal/adapters/progress"
 	"github.com/alejandro/video-worker/internal/core/domain"
 	"github.com/alejandro/video-worker/internal/core/ports"
 	"github.com/google/uuid"
 )
 
+// requeuer is an optional capability a ports.MessageQueue backend may
+// implement to redeliver a message without consuming a delivery attempt,
+// for cases where a refetch isn't a processing failure. HandleNext type-
+// asserts for it on ErrLockHeld rather than calling queue.Fail, since lock
+// contention with another in-flight attempt for the same video is the
+// expected case this feature exists for, not a failing task.
+type requeuer interface {
+	Requeue(ctx context.Context, msg *ports.QueueMessage) error
+}
+
 type ProcessVideoUseCase struct {
 	queue             ports.MessageQueue
 	storage           ports.Storage
@@ -23,6 +38,32 @@ type ProcessVideoUseCase struct {
 	processingTimeout time.Duration
 	maxAttempts       int
 	processedBaseURL  string
+	outputFormat      ports.OutputFormat
+	// progressSink is optional; when set, phase progress is also persisted
+	// there in addition to metrics (e.g. for a frontend to poll).
+	progressSink ports.ProgressSink
+	// presignedURLExpiry is how long a presigned playback URL stays valid.
+	// Zero disables presigning.
+	presignedURLExpiry time.Duration
+	// ledger is optional; when set, HandleNext claims a task before
+	// processing it and completes the claim afterward, so a redelivery of
+	// the same task_id (SQSQueue.Fail requeues, a worker crash and restart)
+	// never double-processes or double-uploads. Nil disables the guard.
+	ledger ports.TaskLedger
+	// leaseTTL is how long a TaskLedger claim is held before it's
+	// considered stale and reapable. Unused when ledger is nil.
+	leaseTTL time.Duration
+	// locker is optional; when set, HandleNext holds a per-VideoID lock for
+	// the duration of processing, so a message redelivered while a previous
+	// attempt for the same video is still in flight is requeued instead of
+	// running concurrently with it. Nil disables the guard.
+	locker ports.TaskLocker
+	// lockTTL is how long an acquired video lock lives before it's
+	// considered abandoned. Unused when locker is nil.
+	lockTTL time.Duration
+	// lockRefreshInterval is how often a held lock's TTL is extended while
+	// processing is still in flight. Zero defaults to lockTTL/3.
+	lockRefreshInterval time.Duration
 }
 
 func NewProcessVideoUseCase(
@@ -35,20 +76,39 @@ func NewProcessVideoUseCase(
 	processingTimeout time.Duration,
 	maxAttempts int,
 	processedBaseURL string,
+	outputFormat ports.OutputFormat,
+	progressSink ports.ProgressSink,
+	presignedURLExpiry time.Duration,
+	ledger ports.TaskLedger,
+	leaseTTL time.Duration,
+	locker ports.TaskLocker,
+	lockTTL time.Duration,
+	lockRefreshInterval time.Duration,
 ) *ProcessVideoUseCase {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if outputFormat == "" {
+		outputFormat = ports.OutputFormatMP4
+	}
 	return &ProcessVideoUseCase{
-		queue:             queue,
-		storage:           storage,
-		repository:        repository,
-		metrics:           metrics,
-		processor:         processor,
-		logger:            logger,
-		processingTimeout: processingTimeout,
-		maxAttempts:       maxAttempts,
-		processedBaseURL:  processedBaseURL,
+		queue:               queue,
+		storage:             storage,
+		repository:          repository,
+		metrics:             metrics,
+		processor:           processor,
+		logger:              logger,
+		processingTimeout:   processingTimeout,
+		maxAttempts:         maxAttempts,
+		processedBaseURL:    processedBaseURL,
+		outputFormat:        outputFormat,
+		progressSink:        progressSink,
+		presignedURLExpiry:  presignedURLExpiry,
+		ledger:              ledger,
+		leaseTTL:            leaseTTL,
+		locker:              locker,
+		lockTTL:             lockTTL,
+		lockRefreshInterval: lockRefreshInterval,
 	}
 }
 
@@ -63,13 +123,41 @@ func (u *ProcessVideoUseCase) HandleNext(ctx context.Context, workerID string) e
 		return err
 	}
 
+	task := msg.Task
+
+	lockToken, err := u.acquireVideoLock(ctx, task.VideoID, workerID)
+	if err != nil {
+		if errors.Is(err, ports.ErrLockHeld) {
+			u.logger.Info("video already locked by another worker, requeuing task",
+				zap.String("video_id", task.VideoID), zap.String("task_id", task.ID))
+			if rq, ok := u.queue.(requeuer); ok {
+				if rqErr := rq.Requeue(ctx, msg); rqErr != nil {
+					u.logger.Warn("failed to requeue lock-contended task", zap.Error(rqErr), zap.String("task_id", task.ID))
+				}
+			} else {
+				u.logger.Warn("queue backend cannot requeue without consuming a delivery attempt; leaving message pending for reclaim",
+					zap.String("task_id", task.ID))
+			}
+			return nil
+		}
+		u.metrics.IncQueueError(workerID)
+		u.logger.Error("failed to acquire video lock", zap.Error(err), zap.String("video_id", task.VideoID))
+		_ = u.queue.Fail(ctx, msg, err)
+		return err
+	}
+	if lockToken != "" {
+		refreshCtx, stopRefresh := context.WithCancel(ctx)
+		defer stopRefresh()
+		go u.runLockRefresh(refreshCtx, task.VideoID, lockToken)
+		defer u.releaseVideoLock(context.Background(), task.VideoID, lockToken)
+	}
+
 	start := time.Now()
 	status := domain.VideoStatusUploaded
 	defer func() {
 		u.metrics.ObserveProcessingDuration(string(status), workerID, time.Since(start))
 	}()
 
-	task := msg.Task
 	video, err := u.repository.FindByID(ctx, task.VideoID)
 	if err != nil {
 		u.metrics.IncTaskProcessed(string(domain.VideoStatusFailed), workerID)
@@ -78,13 +166,28 @@ func (u *ProcessVideoUseCase) HandleNext(ctx context.Context, workerID string) e
 		return err
 	}
 
+	if err := u.claimTask(ctx, task, workerID); err != nil {
+		if errors.Is(err, ports.ErrAlreadyCompleted) {
+			u.logger.Info("task already completed, skipping redelivery", zap.String("task_id", task.ID))
+			if ackErr := u.queue.Ack(ctx, msg); ackErr != nil {
+				u.logger.Error("acknowledgement failed", zap.Error(ackErr), zap.String("task_id", task.ID))
+			}
+			status = domain.VideoStatusProcessed
+			return nil
+		}
+		u.metrics.IncTaskProcessed(string(domain.VideoStatusFailed), workerID)
+		u.logger.Warn("failed to claim task", zap.Error(err), zap.String("task_id", task.ID))
+		_ = u.queue.Fail(ctx, msg, err)
+		return err
+	}
+
 	processCtx := ctx
 	var cancel context.CancelFunc
 	if u.processingTimeout > 0 {
 		processCtx, cancel = context.WithTimeout(ctx, u.processingTimeout)
 		defer cancel()
 	}
-	rawVideoReader, err := u.getVideoBinary(processCtx, task)
+	rawVideoReader, err := u.getVideoBinary(processCtx, task, workerID)
 	if err != nil {
 		video.ResetToUploaded()
 		updateErr := u.repository.Update(ctx, video)
@@ -93,6 +196,7 @@ func (u *ProcessVideoUseCase) HandleNext(ctx context.Context, workerID string) e
 		}
 		u.metrics.IncTaskProcessed(string(domain.VideoStatusFailed), workerID)
 		u.logger.Error("failed to download video", zap.Error(err), zap.String("task_id", task.ID))
+		u.releaseTask(ctx, task, workerID)
 		_ = u.queue.Fail(ctx, msg, err)
 		return err
 	}
@@ -100,7 +204,7 @@ func (u *ProcessVideoUseCase) HandleNext(ctx context.Context, workerID string) e
 		_ = rawVideoReader.Close()
 	}()
 
-	videoProcessedReader, err := u.processVideo(ctx, rawVideoReader)
+	videoProcessedReader, err := u.processVideo(ctx, rawVideoReader, task.ID, workerID)
 	if err != nil {
 		video.ResetToUploaded()
 		updateErr := u.repository.Update(ctx, video)
@@ -114,15 +218,16 @@ func (u *ProcessVideoUseCase) HandleNext(ctx context.Context, workerID string) e
 			u.logger.Warn("max retry attempts reached", zap.String("task_id", task.ID))
 		}
 		status = domain.VideoStatusFailed
+		u.releaseTask(ctx, task, workerID)
 		_ = u.queue.Fail(ctx, msg, err)
 		return err
 	}
 
-	// Generate processed video ID and construct output path
+	// Generate a processed video ID; the upload path (and, for ABR bundles,
+	// the manifest path within it) is decided by uploadProcessedVideo.
 	processedVideoID := uuid.New().String()
-	outputPath := processedVideoID + ".mp4"
 
-	err = u.uploadProcessedVideo(processCtx, outputPath, videoProcessedReader)
+	outputPath, err := u.uploadProcessedVideo(processCtx, processedVideoID, videoProcessedReader, task.ID, workerID)
 	if err != nil {
 		video.ResetToUploaded()
 		updateErr := u.repository.Update(ctx, video)
@@ -132,20 +237,26 @@ func (u *ProcessVideoUseCase) HandleNext(ctx context.Context, workerID string) e
 		u.metrics.IncTaskProcessed(string(domain.VideoStatusFailed), workerID)
 		u.logger.Error("failed to upload processed video", zap.Error(err), zap.String("task_id", task.ID))
 		status = domain.VideoStatusFailed
+		u.releaseTask(ctx, task, workerID)
 		_ = u.queue.Fail(ctx, msg, err)
 		return err
 	}
 
+	playbackURL := u.presignPlaybackURL(processCtx, outputPath)
+
 	processedAt := time.Now()
-	video.MarkProcessed(processedAt, processedVideoID, outputPath)
+	video.MarkProcessed(processedAt, processedVideoID, outputPath, playbackURL)
 	if err := u.repository.Update(ctx, video); err != nil {
 		u.metrics.IncTaskProcessed(string(domain.VideoStatusFailed), workerID)
 		u.logger.Error("failed to mark completed", zap.Error(err), zap.String("video_id", task.VideoID))
 		status = domain.VideoStatusFailed
+		u.releaseTask(ctx, task, workerID)
 		_ = u.queue.Fail(ctx, msg, err)
 		return err
 	}
 
+	u.completeTask(ctx, task, resultHash(outputPath))
+
 	u.metrics.IncTaskProcessed(string(domain.VideoStatusProcessed), workerID)
 	u.logger.Info("video processed successfully", zap.String("task_id", task.ID), zap.String("video_id", video.ID))
 
@@ -157,43 +268,270 @@ func (u *ProcessVideoUseCase) HandleNext(ctx context.Context, workerID string) e
 	return nil
 }
 
-func (u *ProcessVideoUseCase) getVideoBinary(ctx context.Context, task domain.Task) (io.ReadCloser, error) {
+// presignPlaybackURL returns a presigned playback URL for outputPath, or ""
+// if presigning is disabled or unsupported by the storage backend. Presign
+// failures are logged and counted but never fail the task: the video is
+// still marked processed with its raw path.
+func (u *ProcessVideoUseCase) presignPlaybackURL(ctx context.Context, outputPath string) string {
+	if u.presignedURLExpiry <= 0 {
+		return ""
+	}
+
+	playbackURL, err := u.storage.Presign(ctx, outputPath, ports.PresignGet, u.presignedURLExpiry)
+	if err != nil {
+		if !errors.Is(err, ports.ErrUnsupported) {
+			u.metrics.IncPresignErrors()
+			u.logger.Warn("failed to presign playback URL", zap.Error(err), zap.String("output_path", outputPath))
+		}
+		return ""
+	}
+
+	return playbackURL
+}
+
+// claimTask takes ownership of task in the ledger before it's processed.
+// It's a no-op returning nil when no ledger is configured.
+func (u *ProcessVideoUseCase) claimTask(ctx context.Context, task domain.Task, workerID string) error {
+	if u.ledger == nil {
+		return nil
+	}
+	return u.ledger.Claim(ctx, task.ID, task.VideoID, workerID, u.leaseTTL)
+}
+
+// releaseTask gives up task's lease after a failure, so a retry doesn't
+// have to wait out leaseTTL before another worker (or this one) can claim
+// it again.
+func (u *ProcessVideoUseCase) releaseTask(ctx context.Context, task domain.Task, workerID string) {
+	if u.ledger == nil {
+		return
+	}
+	if err := u.ledger.Release(ctx, task.ID, workerID); err != nil {
+		u.logger.Warn("failed to release task ledger lease", zap.Error(err), zap.String("task_id", task.ID))
+	}
+}
+
+// completeTask marks task done in the ledger so a later redelivery of the
+// same task_id is skipped instead of reprocessed.
+func (u *ProcessVideoUseCase) completeTask(ctx context.Context, task domain.Task, resultHash string) {
+	if u.ledger == nil {
+		return
+	}
+	if err := u.ledger.Complete(ctx, task.ID, resultHash); err != nil {
+		u.logger.Warn("failed to complete task ledger lease", zap.Error(err), zap.String("task_id", task.ID))
+	}
+}
+
+// acquireVideoLock takes the TaskLocker lock for videoID, owned by a token
+// unique to this attempt (workerID plus a fresh UUID so two attempts from
+// the same worker, e.g. after a crash and restart, never collide). It's a
+// no-op returning "", nil when no locker is configured.
+func (u *ProcessVideoUseCase) acquireVideoLock(ctx context.Context, videoID, workerID string) (string, error) {
+	if u.locker == nil {
+		return "", nil
+	}
+	token := workerID + ":" + uuid.New().String()
+	if err := u.locker.Acquire(ctx, videoID, token, u.lockTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// runLockRefresh extends videoID's lock every lockRefreshInterval (or
+// lockTTL/3 if unset) until ctx is canceled or the lock is lost, so a
+// transcode that runs long doesn't have its lock expire and get stolen by
+// another worker mid-flight.
+func (u *ProcessVideoUseCase) runLockRefresh(ctx context.Context, videoID, token string) {
+	interval := u.lockRefreshInterval
+	if interval <= 0 {
+		interval = u.lockTTL / 3
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.locker.Refresh(ctx, videoID, token, u.lockTTL); err != nil {
+				if !errors.Is(err, ports.ErrLockHeld) {
+					u.logger.Warn("failed to refresh video lock", zap.Error(err), zap.String("video_id", videoID))
+				}
+				return
+			}
+		}
+	}
+}
+
+// releaseVideoLock gives up videoID's lock if token still owns it. It's a
+// no-op when no locker is configured.
+func (u *ProcessVideoUseCase) releaseVideoLock(ctx context.Context, videoID, token string) {
+	if u.locker == nil {
+		return
+	}
+	if err := u.locker.Release(ctx, videoID, token); err != nil {
+		u.logger.Warn("failed to release video lock", zap.Error(err), zap.String("video_id", videoID))
+	}
+}
+
+// resultHash fingerprints outputPath for TaskLedger.Complete's result_hash
+// column, cheap context for diagnosing a duplicate-looking completion
+// without re-reading the uploaded bytes.
+func resultHash(outputPath string) string {
+	sum := sha256.Sum256([]byte(outputPath))
+	return hex.EncodeToString(sum[:])
+}
+
+func (u *ProcessVideoUseCase) getVideoBinary(ctx context.Context, task domain.Task, workerID string) (io.ReadCloser, error) {
 	reader, err := u.storage.Download(ctx, task.SourcePath)
 	if err != nil {
 		return nil, err
 	}
 	// Don't close here - the caller is responsible for closing the reader
-	return reader, nil
+
+	tracker := progress.NewTracker(u.metrics, u.progressSink, task.ID, "download", workerID, 0)
+	return wrapProgress(reader, tracker.Reader(ctx, reader)), nil
 }
 
-func (u *ProcessVideoUseCase) uploadProcessedVideo(ctx context.Context, outputPath string, processed *ports.ProcessedVideo) error {
+// uploadProcessedVideo uploads processed to storage under processedBaseURL
+// and returns the path that was persisted as the video's processed location.
+// A single progressive file is uploaded directly; an ABR bundle (dash/hls)
+// has each of its segments uploaded alongside the manifest, and the
+// manifest's path is returned.
+func (u *ProcessVideoUseCase) uploadProcessedVideo(ctx context.Context, processedVideoID string, processed *ports.ProcessedVideo, taskID, workerID string) (string, error) {
 	defer func() {
 		_ = processed.Close()
 	}()
 
+	tracker := progress.NewTracker(u.metrics, u.progressSink, taskID, "upload", workerID, 0)
+
+	if processed.Bundle != nil {
+		manifestPath, err := u.uploadProcessedBundle(ctx, processedVideoID, processed.Bundle, tracker)
+		if err != nil {
+			return "", err
+		}
+		tracker.Done(ctx)
+		return manifestPath, nil
+	}
+
+	outputPath := path.Join(u.processedBaseURL, processedVideoID+".mp4")
 	u.logger.Info("attempting to upload processed video",
 		zap.String("output_path", outputPath))
 
-	if err := u.storage.Upload(ctx, outputPath, processed.Reader); err != nil {
+	if err := u.storage.Upload(ctx, outputPath, tracker.Reader(ctx, processed.Reader)); err != nil {
 		u.logger.Error("failed to upload processed video", zap.Error(err), zap.String("output_path", outputPath))
-		return err
+		return "", err
+	}
+
+	if err := u.uploadProcessedArtifacts(ctx, processedVideoID, processed.Artifacts, tracker); err != nil {
+		return "", err
 	}
 
 	u.logger.Info("successfully uploaded processed video", zap.String("output_path", outputPath))
+	tracker.Done(ctx)
+	return outputPath, nil
+}
+
+// uploadProcessedArtifacts uploads processed's side-files (e.g. a
+// thumbnail sprite sheet and its WebVTT cue file) next to the main
+// processed video. A VTT artifact has its ports.SpriteArtifactPlaceholder
+// rewritten to the sprite artifact's real uploaded name first, since the
+// ffmpeg adapter that generated it didn't yet know processedVideoID.
+func (u *ProcessVideoUseCase) uploadProcessedArtifacts(ctx context.Context, processedVideoID string, artifacts []ports.ProcessedArtifact, tracker *progress.Tracker) error {
+	for _, artifact := range artifacts {
+		artifactPath := path.Join(u.processedBaseURL, processedVideoID+artifact.PathSuffix)
+
+		var body io.Reader = artifact.Reader
+		if artifact.ContentType == "text/vtt" {
+			data, err := io.ReadAll(artifact.Reader)
+			if err != nil {
+				u.logger.Error("failed to read thumbnail vtt artifact", zap.Error(err), zap.String("artifact_path", artifactPath))
+				return err
+			}
+			data = bytes.ReplaceAll(data, []byte(ports.SpriteArtifactPlaceholder), []byte(processedVideoID+".sprite.jpg"))
+			body = bytes.NewReader(data)
+		}
+
+		if err := u.storage.Upload(ctx, artifactPath, tracker.Reader(ctx, body)); err != nil {
+			u.logger.Error("failed to upload processed artifact", zap.Error(err), zap.String("artifact_path", artifactPath))
+			return err
+		}
+	}
 	return nil
 }
 
-func (u *ProcessVideoUseCase) processVideo(ctx context.Context, rawVideoReader io.ReadCloser) (*ports.ProcessedVideo, error) {
+// uploadProcessedBundle uploads every segment of an ABR bundle followed by
+// its manifest, all under a per-video prefix, and returns the manifest path.
+func (u *ProcessVideoUseCase) uploadProcessedBundle(ctx context.Context, processedVideoID string, bundle *ports.ProcessedBundle, tracker *progress.Tracker) (string, error) {
+	prefix := path.Join(u.processedBaseURL, processedVideoID)
+
+	for _, seg := range bundle.Segments {
+		segPath := path.Join(prefix, seg.Path)
+		if err := u.storage.Upload(ctx, segPath, tracker.Reader(ctx, seg.Reader)); err != nil {
+			u.logger.Error("failed to upload processed segment", zap.Error(err), zap.String("segment_path", segPath))
+			return "", err
+		}
+	}
+
+	manifestPath := path.Join(prefix, bundle.ManifestPath)
+	if err := u.storage.Upload(ctx, manifestPath, tracker.Reader(ctx, bundle.ManifestReader)); err != nil {
+		u.logger.Error("failed to upload processed manifest", zap.Error(err), zap.String("manifest_path", manifestPath))
+		return "", err
+	}
+
+	u.logger.Info("successfully uploaded processed bundle",
+		zap.String("manifest_path", manifestPath), zap.Int("segments", len(bundle.Segments)))
+	return manifestPath, nil
+}
+
+// namedFile is implemented by storage.Download results already spooled to
+// disk (see internal/adapters/storage/spool), exposing the temp file's path.
+type namedFile interface {
+	Name() string
+}
+
+// progressReadCloser re-pairs a progress-tracked io.Reader with the
+// original reader's Close method.
+type progressReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// namedProgressReadCloser is a progressReadCloser that also forwards Name,
+// so wrapping a spooled-to-disk download for progress reporting never hides
+// the namedFile optimization in processVideo.
+type namedProgressReadCloser struct {
+	progressReadCloser
+	name string
+}
+
+func (p *namedProgressReadCloser) Name() string { return p.name }
+
+// wrapProgress returns a ReadCloser that reads through tracked but closes
+// via original, preserving original's namedFile-ness if it has one.
+func wrapProgress(original io.ReadCloser, tracked io.Reader) io.ReadCloser {
+	base := progressReadCloser{Reader: tracked, Closer: original}
+	if named, ok := original.(namedFile); ok {
+		return &namedProgressReadCloser{progressReadCloser: base, name: named.Name()}
+	}
+	return &base
+}
+
+func (u *ProcessVideoUseCase) processVideo(ctx context.Context, rawVideoReader io.ReadCloser, taskID, workerID string) (*ports.ProcessedVideo, error) {
 	if u.processor == nil {
 		return nil, errors.New("video processor not configured")
 	}
 
-	processed, err := u.processor.Process(ctx, rawVideoReader, ports.VideoProcessingOptions{
+	opts := ports.VideoProcessingOptions{
 		ClipDuration: 30 * time.Second,
 		TargetWidth:  720,
 		TargetHeight: 1280,
 		TargetFormat: "mp4",
 		RemoveAudio:  true,
+		OutputFormat: u.outputFormat,
 		Watermark: &ports.WatermarkOptions{
 			Text:          "ANB Rising Stars",
 			FontColor:     "white",
@@ -206,10 +544,30 @@ func (u *ProcessVideoUseCase) processVideo(ctx context.Context, rawVideoReader i
 			StartDuration: 3 * time.Second,
 			EndDuration:   3 * time.Second,
 		},
-	})
+	}
+
+	tracker := progress.NewTracker(u.metrics, u.progressSink, taskID, "transcode", workerID, 0)
+
+	// When the source video is already spooled to a local file and the
+	// processor can read from a path directly, skip re-copying it into
+	// another temp file. ffmpeg reads the file itself in this case, so no
+	// bytes flow through tracker; it just reports the phase as done.
+	if pathAware, ok := u.processor.(ports.PathAwareVideoProcessor); ok {
+		if named, ok := rawVideoReader.(namedFile); ok {
+			processed, err := pathAware.ProcessPath(ctx, named.Name(), opts)
+			if err != nil {
+				return nil, err
+			}
+			tracker.Done(ctx)
+			return processed, nil
+		}
+	}
+
+	processed, err := u.processor.Process(ctx, tracker.Reader(ctx, rawVideoReader), opts)
 	if err != nil {
 		return nil, err
 	}
+	tracker.Done(ctx)
 
 	return processed, nil
 }
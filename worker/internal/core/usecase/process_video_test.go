@@ -81,9 +81,14 @@ func TestHandleNextSuccess(t *testing.T) {
 	})
 	metrics.EXPECT().IncTaskProcessed(string(domain.VideoStatusProcessed), "1")
 	metrics.EXPECT().ObserveProcessingDuration(string(domain.VideoStatusProcessed), "1", gomock.Any())
+	metrics.EXPECT().ObserveBytesTransferred("download", "1", gomock.Any()).AnyTimes()
+	metrics.EXPECT().ObserveBytesTransferred("transcode", "1", gomock.Any()).AnyTimes()
+	metrics.EXPECT().ObserveBytesTransferred("upload", "1", gomock.Any()).AnyTimes()
+	metrics.EXPECT().SetTaskProgress(task.ID, "transcode", 100.0)
+	metrics.EXPECT().SetTaskProgress(task.ID, "upload", 100.0)
 	queue.EXPECT().Ack(ctx, message).Return(nil)
 
-	uc := NewProcessVideoUseCase(queue, storage, repository, metrics, processor, zap.NewNop(), 0, 3, "/processed/")
+	uc := NewProcessVideoUseCase(queue, storage, repository, metrics, processor, zap.NewNop(), 0, 3, "/processed/", ports.OutputFormatMP4, nil, 0, nil, 0, nil, 0, 0)
 
 	err := uc.HandleNext(ctx, "1")
 	if err != nil {
@@ -99,7 +104,7 @@ func TestHandleNextQueueNoMessages(t *testing.T) {
 	queue := mocks.NewMockMessageQueue(ctrl)
 	queue.EXPECT().Fetch(ctx).Return(nil, ports.ErrNoMessages)
 
-	uc := NewProcessVideoUseCase(queue, nil, nil, nil, nil, zap.NewNop(), 0, 3, "/processed/")
+	uc := NewProcessVideoUseCase(queue, nil, nil, nil, nil, zap.NewNop(), 0, 3, "/processed/", ports.OutputFormatMP4, nil, 0, nil, 0, nil, 0, 0)
 
 	err := uc.HandleNext(ctx, "1")
 	if err != nil {
@@ -119,7 +124,7 @@ func TestHandleNextQueueFetchError(t *testing.T) {
 	queue.EXPECT().Fetch(ctx).Return(nil, expectedErr)
 	metrics.EXPECT().IncQueueError("1")
 
-	uc := NewProcessVideoUseCase(queue, nil, nil, metrics, nil, zap.NewNop(), 0, 3, "/processed/")
+	uc := NewProcessVideoUseCase(queue, nil, nil, metrics, nil, zap.NewNop(), 0, 3, "/processed/", ports.OutputFormatMP4, nil, 0, nil, 0, nil, 0, 0)
 
 	err := uc.HandleNext(ctx, "1")
 	if !errors.Is(err, expectedErr) {
@@ -151,7 +156,7 @@ func TestHandleNextFindByIDError(t *testing.T) {
 	queue.EXPECT().Fail(ctx, message, expectedErr).Return(nil)
 	metrics.EXPECT().ObserveProcessingDuration(string(domain.VideoStatusUploaded), "1", gomock.Any())
 
-	uc := NewProcessVideoUseCase(queue, nil, repository, metrics, nil, zap.NewNop(), 0, 3, "/processed/")
+	uc := NewProcessVideoUseCase(queue, nil, repository, metrics, nil, zap.NewNop(), 0, 3, "/processed/", ports.OutputFormatMP4, nil, 0, nil, 0, nil, 0, 0)
 
 	err := uc.HandleNext(context.Background(), "1")
 	if !errors.Is(err, expectedErr) {
@@ -194,7 +199,7 @@ func TestHandleNextProcessVideoError(t *testing.T) {
 	queue.EXPECT().Fail(ctx, message, processErr).Return(nil)
 	metrics.EXPECT().ObserveProcessingDuration(string(domain.VideoStatusFailed), "1", gomock.Any())
 
-	uc := NewProcessVideoUseCase(queue, storage, repository, metrics, processor, zap.NewNop(), 0, 3, "/processed/")
+	uc := NewProcessVideoUseCase(queue, storage, repository, metrics, processor, zap.NewNop(), 0, 3, "/processed/", ports.OutputFormatMP4, nil, 0, nil, 0, nil, 0, 0)
 
 	err := uc.HandleNext(ctx, "1")
 	if !errors.Is(err, processErr) {
@@ -230,7 +235,7 @@ func TestProcessVideoSuccess(t *testing.T) {
 	})
 
 	uc := &ProcessVideoUseCase{processor: processor}
-	result, err := uc.processVideo(ctx, io.NopCloser(bytes.NewBufferString("payload")))
+	result, err := uc.processVideo(ctx, io.NopCloser(bytes.NewBufferString("payload")), "task-1", "1")
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -261,7 +266,7 @@ func TestGetVideoBinaryDownloadError(t *testing.T) {
 
 	uc := &ProcessVideoUseCase{storage: storage}
 
-	_, err := uc.getVideoBinary(ctx, domain.Task{SourcePath: "source.mp4"})
+	_, err := uc.getVideoBinary(ctx, domain.Task{SourcePath: "source.mp4"}, "1")
 	if !errors.Is(err, expectedErr) {
 		t.Fatalf("expected %v, got %v", expectedErr, err)
 	}
@@ -276,11 +281,11 @@ func TestProcessVideoUploadError(t *testing.T) {
 	processor := mocks.NewMockVideoProcessor(ctrl)
 	expectedErr := errors.New("upload error")
 
-	storage.EXPECT().Upload(ctx, "output.mp4", gomock.Any()).Return(expectedErr)
+	storage.EXPECT().Upload(ctx, "processed/video-1.mp4", gomock.Any()).Return(expectedErr)
 
-	uc := &ProcessVideoUseCase{storage: storage, processor: processor, logger: zap.NewNop()}
+	uc := &ProcessVideoUseCase{storage: storage, processor: processor, logger: zap.NewNop(), processedBaseURL: "processed/"}
 
-	err := uc.uploadProcessedVideo(ctx, "output.mp4", &ports.ProcessedVideo{Reader: io.NopCloser(bytes.NewBufferString("processed"))})
+	_, err := uc.uploadProcessedVideo(ctx, "video-1", &ports.ProcessedVideo{Reader: io.NopCloser(bytes.NewBufferString("processed"))}, "task-1", "1")
 	if !errors.Is(err, expectedErr) {
 		t.Fatalf("expected %v, got %v", expectedErr, err)
 	}
@@ -292,7 +297,7 @@ func TestProcessVideoWithoutProcessor(t *testing.T) {
 	t.Cleanup(ctrl.Finish)
 
 	uc := &ProcessVideoUseCase{}
-	_, err := uc.processVideo(ctx, io.NopCloser(bytes.NewBufferString("payload")))
+	_, err := uc.processVideo(ctx, io.NopCloser(bytes.NewBufferString("payload")), "task-1", "1")
 	if err == nil {
 		t.Fatalf("expected error when processor is not configured")
 	}
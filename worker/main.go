@@ -18,18 +18,31 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/alejandro/video-worker/internal/adapters/adminapi"
 	"github.com/alejandro/video-worker/internal/adapters/config"
 	"github.com/alejandro/video-worker/internal/adapters/ffmpeg"
 	metricsadapter "github.com/alejandro/video-worker/internal/adapters/metrics"
 	nextcloudadapter "github.com/alejandro/video-worker/internal/adapters/nextcloud"
 	postgresadapter "github.com/alejandro/video-worker/internal/adapters/postgres"
+	"github.com/alejandro/video-worker/internal/adapters/queue"
 	redisadapter "github.com/alejandro/video-worker/internal/adapters/redis"
-	sqsadapter "github.com/alejandro/video-worker/internal/adapters/sqs"
 	s3adapter "github.com/alejandro/video-worker/internal/adapters/s3"
 	"github.com/alejandro/video-worker/internal/core/ports"
 	"github.com/alejandro/video-worker/internal/core/usecase"
 )
 
+// reclaimer is implemented by queue backends with a crashed-consumer
+// pending-entries sweep (currently only redis.StreamQueue).
+type reclaimer interface {
+	RunReclaimer(ctx context.Context, interval time.Duration)
+}
+
+// retryScheduler is implemented by queue backends with a delayed-retry
+// promotion loop (currently only redis.StreamQueue).
+type retryScheduler interface {
+	RunRetryScheduler(ctx context.Context)
+}
+
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -79,6 +92,26 @@ func Run(ctx context.Context) error {
 
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	// Dead-letter triage is only wired up for the redis queue backend today;
+	// sqs and kafka don't yet have a DeadLetterStore implementation.
+	if cfg.QueueBackend == "redis" {
+		adminRedisClient := redislib.NewClient(&redislib.Options{
+			Addr:     cfg.RedisAddr,
+			Username: cfg.RedisUsername,
+			Password: cfg.RedisPassword,
+		})
+		defer func() {
+			if cerr := adminRedisClient.Close(); cerr != nil {
+				logger.Warn("failed to close admin redis client", zap.Error(cerr))
+			}
+		}()
+		dlqStore := redisadapter.NewDeadLetterStore(adminRedisClient, cfg.RedisDLQStream, cfg.RedisStream, logger)
+		dlqHandler := adminapi.NewHandler(dlqStore, logger)
+		metricsMux.Handle("/admin/dlq", dlqHandler)
+		metricsMux.Handle("/admin/dlq/", dlqHandler)
+	}
+
 	metricsSrv := &http.Server{
 		Addr:              cfg.MetricsAddr,
 		Handler:           metricsMux,
@@ -117,6 +150,8 @@ func Run(ctx context.Context) error {
 			cfg.NextcloudRoot,
 			cfg.NextcloudUsername,
 			cfg.NextcloudPassword,
+			cfg.VideoTempDir,
+			cfg.DownloadSpoolThreshold,
 			logger,
 		)
 		if err != nil {
@@ -133,7 +168,12 @@ func Run(ctx context.Context) error {
 			cfg.S3AccessKey,
 			cfg.S3SecretKey,
 			cfg.S3Endpoint,
+			cfg.S3PartSizeBytes,
+			cfg.S3UploadConcurrency,
+			cfg.VideoTempDir,
+			cfg.DownloadSpoolThreshold,
 			logger,
+			metricsAdapter,
 		)
 		if err != nil {
 			return fmt.Errorf("init s3 storage: %w", err)
@@ -148,7 +188,36 @@ func Run(ctx context.Context) error {
 	}
 
 	repository := postgresadapter.NewVideoRepository(db, logger)
-	processor := ffmpeg.NewVideoProcessor(os.Getenv("FFMPEG_PATH"), os.Getenv("FFPROBE_PATH"), os.Getenv("VIDEO_TEMP_DIR"), logger)
+	taskLedger := postgresadapter.NewTaskLedger(db, logger)
+
+	// The TaskLocker guards against two workers concurrently transcoding
+	// the same VideoID after StreamQueue.Fail requeues a task or the
+	// reclaimer reaps a crashed consumer's pending message; it's only
+	// wired up for the redis queue backend today.
+	var taskLocker ports.TaskLocker
+	if cfg.LockEnabled && cfg.QueueBackend == "redis" {
+		lockRedisClient := redislib.NewClient(&redislib.Options{
+			Addr:     cfg.RedisAddr,
+			Username: cfg.RedisUsername,
+			Password: cfg.RedisPassword,
+		})
+		defer func() {
+			if cerr := lockRedisClient.Close(); cerr != nil {
+				logger.Warn("failed to close lock redis client", zap.Error(cerr))
+			}
+		}()
+		taskLocker = redisadapter.NewTaskLocker(lockRedisClient)
+	}
+	rawProcessor := ffmpeg.NewVideoProcessor(
+		os.Getenv("FFMPEG_PATH"), os.Getenv("FFPROBE_PATH"), cfg.VideoTempDir, os.Getenv("FFMPEG_FORCE_ENCODER"),
+		cfg.ChunkWorkerPoolSize, logger, metricsAdapter,
+	)
+
+	// All workers submit transcode jobs through a single shared pool so the
+	// number of live ffmpeg/ffprobe subprocesses is bounded by
+	// FFMPEG_WORKER_POOL_SIZE regardless of WORKER_POOL_SIZE (queue
+	// consumer) count.
+	processorPool := ffmpeg.NewWorkerPool(rawProcessor, cfg.FFmpegWorkerPoolSize, cfg.FFmpegJobQueueSize, logger, metricsAdapter)
 
 	workerCount := cfg.WorkerPoolSize
 	if workerCount <= 0 {
@@ -157,6 +226,7 @@ func Run(ctx context.Context) error {
 
 	logger.Info("video worker running",
 		zap.Int("worker_pool_size", workerCount),
+		zap.Int("ffmpeg_worker_pool_size", cfg.FFmpegWorkerPoolSize),
 		zap.Duration("processing_timeout", cfg.ProcessingTimeout),
 		zap.String("queue_backend", cfg.QueueBackend),
 	)
@@ -164,6 +234,20 @@ func Run(ctx context.Context) error {
 	workerCtx, cancelWorkers := context.WithCancel(ctx)
 	defer cancelWorkers()
 
+	var poolWg sync.WaitGroup
+	poolWg.Add(1)
+	go func() {
+		defer poolWg.Done()
+		processorPool.Run(workerCtx)
+	}()
+	defer poolWg.Wait()
+
+	poolWg.Add(1)
+	go func() {
+		defer poolWg.Done()
+		taskLedger.RunReaper(workerCtx, cfg.TaskReaperInterval)
+	}()
+
 	var wg sync.WaitGroup
 	for i := 0; i < workerCount; i++ {
 		workerID := i + 1
@@ -172,13 +256,9 @@ func Run(ctx context.Context) error {
 			defer wg.Done()
 			workerIDStr := fmt.Sprintf("%d", id)
 
-			// Create queue instance based on configured backend
-			var queue ports.MessageQueue
-			var err error
-
-			switch cfg.QueueBackend {
-			case "redis":
-				redisClient := redislib.NewClient(&redislib.Options{
+			var redisClient *redislib.Client
+			if cfg.QueueBackend == "redis" {
+				redisClient = redislib.NewClient(&redislib.Options{
 					Addr:     cfg.RedisAddr,
 					Username: cfg.RedisUsername,
 					Password: cfg.RedisPassword,
@@ -192,30 +272,53 @@ func Run(ctx context.Context) error {
 					logger.Error("failed to connect to redis", zap.Int("worker_id", id), zap.Error(err))
 					return
 				}
-				consumerName := fmt.Sprintf("%s-worker-%d", cfg.RedisConsumer, id)
-				queue, err = redisadapter.NewStreamQueue(ctx, redisClient, cfg.RedisStream, cfg.RedisGroup, consumerName, cfg.RedisBlockTimeout, cfg.MaxDeliveries, logger, metricsAdapter)
-			case "sqs":
-				queue, err = sqsadapter.NewSQSQueue(ctx, cfg.SQSQueueURL, cfg.SQSRegion, cfg.MaxDeliveries, cfg.SQSWaitTime, logger, metricsAdapter)
-			default:
-				logger.Error("unsupported queue backend", zap.String("backend", cfg.QueueBackend))
-				return
 			}
 
+			consumerName := fmt.Sprintf("%s-worker-%d", cfg.RedisConsumer, id)
+			mq, err := queue.New(ctx, cfg, queue.Options{
+				RedisClient:  redisClient,
+				ConsumerName: consumerName,
+				Logger:       logger,
+				Metrics:      metricsAdapter,
+			})
 			if err != nil {
 				logger.Error("failed to create queue for worker", zap.Int("worker_id", id), zap.Error(err))
 				return
 			}
 
+			if rc, ok := mq.(reclaimer); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rc.RunReclaimer(workerCtx, cfg.RedisClaimInterval)
+				}()
+			}
+			if rs, ok := mq.(retryScheduler); ok {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rs.RunRetryScheduler(workerCtx)
+				}()
+			}
+
 			useCase := usecase.NewProcessVideoUseCase(
-				queue,
+				mq,
 				storage,
 				repository,
 				metricsAdapter,
-				processor,
+				processorPool,
 				logger,
 				cfg.ProcessingTimeout,
 				cfg.MaxDeliveries,
 				cfg.ProcessedBaseURL,
+				ports.OutputFormat(cfg.OutputFormat),
+				nil, // no ProgressSink adapter configured yet
+				cfg.PresignedURLExpiry,
+				taskLedger,
+				cfg.TaskLeaseTTL,
+				taskLocker,
+				cfg.LockTTL,
+				cfg.LockRefreshInterval,
 			)
 
 			for {